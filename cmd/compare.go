@@ -0,0 +1,100 @@
+package cmd
+
+import (
+    "fmt"
+    "os"
+
+    "github.com/spf13/cobra"
+    "kmget/pkg/client"
+    "kmget/pkg/configmap"
+)
+
+var (
+    ignoreKeys []string
+)
+
+// compareCmd represents the compare command
+var compareCmd = &cobra.Command{
+    Use:   "compare LEFT RIGHT",
+    Short: "Compare ConfigMaps across clusters, contexts or namespaces",
+    Long: `Compare two ConfigMaps, or all ConfigMaps of two namespaces, key by key.
+
+Sources are written as [[context/]namespace/]name. The context defaults to the
+current one (or --context) and the namespace to the context's namespace (or
+--namespace). Use * as the name to compare whole namespaces, matching
+ConfigMaps by name. Keys are reported as identical, changed, or only present on
+one side, with unified diffs for changed text values. The command exits with
+status 2 when the sources differ.
+
+Examples:
+  # Compare a ConfigMap between two clusters
+  kmget compare prod/payments/app-config staging/payments/app-config
+
+  # Compare every ConfigMap of two namespaces in the current cluster
+  kmget compare payments/* payments-v2/*
+
+  # Confirm that only the expected keys differ
+  kmget compare prod/payments/app-config staging/payments/app-config --ignore-key 'DB_*' --ignore-key ENVIRONMENT`,
+    Args: cobra.ExactArgs(2),
+    Run: func(cmd *cobra.Command, args []string) {
+        printer := newPrinter()
+
+        left, err := configmap.ParseCompareSource(args[0])
+        if err != nil {
+            fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+            os.Exit(1)
+        }
+        right, err := configmap.ParseCompareSource(args[1])
+        if err != nil {
+            fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+            os.Exit(1)
+        }
+
+        clients := make(map[string]*client.Client)
+        leftOps := compareOperations(clients, &left)
+        rightOps := compareOperations(clients, &right)
+
+        results, err := configmap.Compare(cmd.Context(), leftOps, left, rightOps, right, configmap.CompareOptions{IgnoreKeys: ignoreKeys})
+        if err != nil {
+            fmt.Fprintf(os.Stderr, "Error comparing ConfigMaps: %v\n", err)
+            os.Exit(1)
+        }
+
+        exitOnPrintError(printer.PrintCompareResults(results))
+        for _, result := range results {
+            if result.HasDifferences() {
+                os.Exit(exitDrift)
+            }
+        }
+    },
+}
+
+// compareOperations connects to the context of source, reusing clients across
+// sources, and fills in the context's namespace when source has none
+func compareOperations(clients map[string]*client.Client, source *configmap.CompareSource) *configmap.Operations {
+    cfg := clientConfig()
+    if source.Context != "" {
+        cfg.Context = source.Context
+    }
+
+    k8sClient, ok := clients[cfg.Context]
+    if !ok {
+        var err error
+        k8sClient, err = client.NewClient(cfg)
+        if err != nil {
+            fmt.Fprintf(os.Stderr, "Error creating Kubernetes client for '%s': %v\n", source, err)
+            os.Exit(1)
+        }
+        clients[cfg.Context] = k8sClient
+    }
+
+    if source.Namespace == "" {
+        source.Namespace = k8sClient.Namespace
+    }
+    return configmap.NewOperations(k8sClient.Clientset)
+}
+
+func init() {
+    compareCmd.Flags().StringArrayVar(&ignoreKeys, "ignore-key", nil, "glob of keys expected to differ, left out of the comparison (repeatable)")
+    rootCmd.AddCommand(compareCmd)
+}
@@ -0,0 +1,133 @@
+package cmd
+
+import (
+    "context"
+    "fmt"
+    "os"
+    "path/filepath"
+    "strings"
+    "sync"
+
+    "github.com/spf13/cobra"
+    "kmget/pkg/client"
+    "kmget/pkg/display"
+    "kmget/pkg/kmget"
+)
+
+var (
+    contextNames []string
+    allContexts  bool
+)
+
+// target is one cluster a command runs against
+type target struct {
+    context   string // empty unless fanning out over contexts
+    client    *kmget.Client
+    namespace string
+    outputDir string
+    printer   *display.Printer
+}
+
+// addContextFlags registers the multi-cluster flags on cmd
+func addContextFlags(cmd *cobra.Command) {
+    cmd.Flags().StringSliceVar(&contextNames, "contexts", nil, "kubeconfig contexts to run against in parallel; output is nested under <output>/<context>")
+    cmd.Flags().BoolVar(&allContexts, "all-contexts", false, "run against every kubeconfig context in parallel")
+}
+
+// runTargets runs fn against the current context, or against every context
+// selected with --contexts or --all-contexts in parallel. fn reports whether
+// the run only partially succeeded. It exits with the combined status.
+func runTargets(ctx context.Context, printer *display.Printer, fn func(ctx context.Context, t *target) (bool, error)) {
+    contexts := contextNames
+    if allContexts {
+        names, err := client.ListContexts(clientConfig())
+        if err != nil {
+            fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+            os.Exit(1)
+        }
+        contexts = names
+    }
+
+    if len(contexts) == 0 {
+        c := newLibraryClient()
+        partial, err := fn(ctx, &target{
+            client:    c,
+            namespace: c.Namespace(),
+            outputDir: outputDir,
+            printer:   printer,
+        })
+        if err != nil {
+            fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+            os.Exit(1)
+        }
+        if partial {
+            os.Exit(exitPartialFailure)
+        }
+        return
+    }
+
+    if kubeContext != "" {
+        fmt.Fprintf(os.Stderr, "Error: --context cannot be combined with --contexts or --all-contexts\n")
+        os.Exit(1)
+    }
+
+    printers := make([]*display.Printer, len(contexts))
+    errs := make([]error, len(contexts))
+    partials := make([]bool, len(contexts))
+    var wg sync.WaitGroup
+    for i, name := range contexts {
+        printers[i] = printer.ForContext(name)
+        wg.Add(1)
+        go func() {
+            defer wg.Done()
+
+            opts := libraryOptions()
+            opts.Context = name
+            c, err := kmget.New(opts)
+            if err != nil {
+                errs[i] = err
+                return
+            }
+            partials[i], errs[i] = fn(ctx, &target{
+                context:   name,
+                client:    c,
+                namespace: c.Namespace(),
+                outputDir: filepath.Join(outputDir, contextDir(name)),
+                printer:   printers[i],
+            })
+        }()
+    }
+    wg.Wait()
+
+    exitOnPrintError(printer.PrintContexts(printers, errs))
+
+    failed, partial := 0, false
+    for i, err := range errs {
+        if err != nil {
+            fmt.Fprintf(os.Stderr, "Error [%s]: %v\n", contexts[i], err)
+            failed++
+        }
+        partial = partial || partials[i]
+    }
+    switch {
+    case failed == len(contexts):
+        os.Exit(1)
+    case failed > 0 || partial:
+        os.Exit(exitPartialFailure)
+    }
+}
+
+// contextDir turns a context name into a single directory name. Context
+// names such as EKS ARNs may contain slashes.
+func contextDir(name string) string {
+    dir := strings.Map(func(r rune) rune {
+        if r == '/' || os.IsPathSeparator(uint8(r)) {
+            return '_'
+        }
+        return r
+    }, name)
+    if dir == "." || dir == ".." {
+        dir = "_" + dir
+    }
+    return dir
+}
@@ -0,0 +1,81 @@
+package cmd
+
+import (
+    "errors"
+    "fmt"
+    "os"
+
+    "github.com/spf13/cobra"
+    "kmget/pkg/kmget"
+)
+
+// exitDrift is the exit code used by diff when local files have drifted
+const exitDrift = 2
+
+// diffCmd represents the diff command
+var diffCmd = &cobra.Command{
+    Use:   "diff [CONFIGMAP_NAME]",
+    Short: "Compare local files against live ConfigMaps",
+    Long: `Compare the files a pull would write against what is in the output directory.
+
+Keys present only locally are reported as added, keys present only in the
+ConfigMap as removed. Text keys are shown as unified diffs, binary keys are
+compared by SHA-256 hash. The command exits with status 2 when drift is found,
+so it can be used to gate CI pipelines, and with status 3 when some namespaces
+could not be compared.
+
+Examples:
+  # Compare a ConfigMap with ./config
+  kmget diff my-config --namespace default --output ./config
+
+  # Compare a tree written by 'kmget pull --all-namespaces'
+  kmget diff --all-namespaces --output ./all-configs
+
+  # Compare a tree pulled with a custom layout
+  kmget diff --all-namespaces --layout ns/cm/key --output ./all-configs`,
+    Args: func(cmd *cobra.Command, args []string) error {
+        if !allNamespaces && len(args) == 0 {
+            return fmt.Errorf("ConfigMap name is required when not using --all-namespaces flag")
+        }
+        return nil
+    },
+    Run: func(cmd *cobra.Command, args []string) {
+        printer := newPrinter()
+        layout := parseLayout()
+
+        c := newLibraryClient()
+
+        opts := kmget.DiffOptions{
+            Namespace:     namespace,
+            AllNamespaces: allNamespaces,
+            Dir:           outputDir,
+            Layout:        layout,
+        }
+        if len(args) > 0 {
+            opts.Name = args[0]
+        }
+        results, err := c.Diff(cmd.Context(), opts)
+        var partialErr *kmget.PartialError
+        if errors.As(err, &partialErr) {
+            warnFailures(partialErr.Failures)
+        } else if err != nil {
+            fmt.Fprintf(os.Stderr, "Error diffing ConfigMaps: %v\n", err)
+            os.Exit(1)
+        }
+
+        exitOnPrintError(printer.PrintDiffResults(results))
+        for _, result := range results {
+            if result.HasDrift() {
+                os.Exit(exitDrift)
+            }
+        }
+        if partialErr != nil {
+            os.Exit(exitPartialFailure)
+        }
+    },
+}
+
+func init() {
+    addLayoutFlag(diffCmd)
+    rootCmd.AddCommand(diffCmd)
+}
@@ -0,0 +1,95 @@
+package cmd
+
+import (
+    "fmt"
+    "os"
+
+    "github.com/spf13/cobra"
+    "kmget/pkg/kmget"
+)
+
+var (
+    getKeys   []string
+    getPretty bool
+)
+
+// getCmd represents the get command
+var getCmd = &cobra.Command{
+    Use:   "get CONFIGMAP_NAME [KEY]",
+    Short: "Print the value of a ConfigMap key to stdout",
+    Long: `Print the value of a ConfigMap (or Secret) key to stdout without writing files.
+
+Values are printed as stored: binaryData keys and Secret values as raw bytes,
+without a trailing newline added. Without a key, the names of all keys are
+listed. --key selects keys by glob pattern and can be repeated; when several
+keys are selected, each value is preceded by a "==> key <==" header.
+
+Examples:
+  # Print one key
+  kmget get app-config app.properties
+
+  # Pipe a value into another tool
+  kmget get app-config settings.json | jq .port
+
+  # List the keys of a ConfigMap
+  kmget get app-config
+
+  # Print every YAML key, re-indented
+  kmget get app-config --key '*.yaml' --pretty
+
+  # Print a Secret value
+  kmget get db-credentials password --kind secret`,
+    Args: cobra.RangeArgs(1, 2),
+    Run: func(cmd *cobra.Command, args []string) {
+        selected, err := resolveKind()
+        if err != nil {
+            fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+            os.Exit(1)
+        }
+        keys := getKeys
+        if len(args) > 1 {
+            keys = append(keys, args[1])
+        }
+
+        c := newLibraryClient()
+
+        result, err := c.Get(cmd.Context(), kmget.GetOptions{
+            Name:      args[0],
+            Namespace: namespace,
+            Kind:      selected,
+            Keys:      keys,
+            Pretty:    getPretty,
+        })
+        if err != nil {
+            fmt.Fprintf(os.Stderr, "Error getting %s: %v\n", args[0], err)
+            os.Exit(1)
+        }
+
+        if len(keys) == 0 {
+            for _, key := range result.Keys {
+                fmt.Println(key)
+            }
+            return
+        }
+        for i, value := range result.Values {
+            if len(result.Values) > 1 {
+                if i > 0 {
+                    fmt.Println()
+                }
+                fmt.Printf("==> %s <==\n", value.Key)
+            }
+            if _, err := os.Stdout.Write(value.Data); err != nil {
+                fmt.Fprintf(os.Stderr, "Error writing value: %v\n", err)
+                os.Exit(1)
+            }
+        }
+    },
+}
+
+func init() {
+    rootCmd.AddCommand(getCmd)
+
+    getCmd.Flags().StringArrayVar(&getKeys, "key", nil, "glob pattern of the keys to print (repeatable)")
+    getCmd.Flags().BoolVar(&getPretty, "pretty", false, "indent JSON values and re-indent YAML values")
+    getCmd.Flags().StringVar(&kind, "kind", "configmap", "kind of resource to read: configmap, secret or all")
+}
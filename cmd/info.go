@@ -5,8 +5,6 @@ import (
     "os"
 
     "github.com/spf13/cobra"
-    "kmget/pkg/client"
-    "kmget/pkg/display"
 )
 
 // infoCmd represents the info command
@@ -15,19 +13,17 @@ var infoCmd = &cobra.Command{
     Short: "Display cluster information",
     Long:  `Display detailed information about the current Kubernetes cluster connection.`,
     Run: func(cmd *cobra.Command, args []string) {
-        k8sClient, err := client.NewClient(kubeconfig)
-        if err != nil {
-            fmt.Fprintf(os.Stderr, "Error creating Kubernetes client: %v\n", err)
-            os.Exit(1)
-        }
+        printer := newPrinter()
+
+        k8sClient := newClient()
 
-        info, err := k8sClient.GetClusterInfo(kubeconfig)
+        info, err := k8sClient.GetClusterInfo(cmd.Context())
         if err != nil {
             fmt.Fprintf(os.Stderr, "Error retrieving cluster info: %v\n", err)
             os.Exit(1)
         }
 
-        display.PrintClusterInfo(info)
+        exitOnPrintError(printer.PrintClusterInfo(info))
     },
 }
 
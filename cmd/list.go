@@ -1,20 +1,24 @@
 package cmd
 
 import (
+    "context"
     "fmt"
     "os"
 
     "github.com/spf13/cobra"
-    "kmget/pkg/client"
-    "kmget/pkg/configmap"
     "kmget/pkg/display"
+    "kmget/pkg/kmget"
+)
+
+var (
+    maskSecrets bool
 )
 
 // listCmd represents the list command
 var listCmd = &cobra.Command{
     Use:   "list",
-    Short: "List ConfigMaps",
-    Long: `List ConfigMaps in the specified namespace or across all namespaces.
+    Short: "List ConfigMaps and Secrets",
+    Long: `List ConfigMaps (and optionally Secrets) in the specified namespace or across all namespaces.
 
 Examples:
   # List ConfigMaps in default namespace
@@ -24,34 +28,76 @@ Examples:
   kmget list --namespace kube-system
 
   # List ConfigMaps across all namespaces
-  kmget list --all-namespaces`,
+  kmget list --all-namespaces
+
+  # List ConfigMaps with a given label across all namespaces
+  kmget list --all-namespaces --selector app=payments
+
+  # List Secrets alongside ConfigMaps, hiding value sizes
+  kmget list --kind all --mask-secrets
+
+  # List ConfigMaps on several clusters at once
+  kmget list --contexts prod-eu,prod-us -n payments`,
     Run: func(cmd *cobra.Command, args []string) {
-        k8sClient, err := client.NewClient(kubeconfig)
+        printer := newPrinter()
+
+        selected, err := resolveKind()
         if err != nil {
-            fmt.Fprintf(os.Stderr, "Error creating Kubernetes client: %v\n", err)
+            fmt.Fprintf(os.Stderr, "Error: %v\n", err)
             os.Exit(1)
         }
 
-        ops := configmap.NewOperations(k8sClient.Clientset)
+        runTargets(cmd.Context(), printer, func(ctx context.Context, t *target) (bool, error) {
+            return runList(ctx, cmd, t, selected)
+        })
+    },
+}
 
+// runList lists the selected kinds on one cluster and reports whether some
+// namespaces could not be listed
+func runList(ctx context.Context, cmd *cobra.Command, t *target, selected string) (bool, error) {
+    selectors := listOptions(cmd)
+    result, err := t.client.List(ctx, kmget.ListOptions{
+        Namespace:       t.namespace,
+        AllNamespaces:   allNamespaces,
+        Kind:            selected,
+        LabelSelector:   selectors.LabelSelector,
+        FieldSelector:   selectors.FieldSelector,
+        ContinueOnError: selectors.ContinueOnError,
+        WithConsumers:   outputFormat == display.FormatWide,
+    })
+    if err != nil && !kmget.IsPartial(err) {
+        return false, err
+    }
+    for _, warning := range result.Warnings {
+        fmt.Fprintf(os.Stderr, "Warning: cannot look up consumers: %v\n", warning)
+    }
+
+    if result.ConfigMaps != nil {
         if allNamespaces {
-            allConfigMaps, err := ops.ListAllConfigMaps()
-            if err != nil {
-                fmt.Fprintf(os.Stderr, "Error listing ConfigMaps: %v\n", err)
-                os.Exit(1)
-            }
-            display.PrintAllConfigMapsList(allConfigMaps)
+            exitOnPrintError(t.printer.PrintAllConfigMapsList(result.ConfigMaps))
         } else {
-            configMaps, err := ops.ListConfigMaps(namespace)
-            if err != nil {
-                fmt.Fprintf(os.Stderr, "Error listing ConfigMaps: %v\n", err)
-                os.Exit(1)
-            }
-            display.PrintConfigMapsList(namespace, configMaps)
+            exitOnPrintError(t.printer.PrintConfigMapsList(t.namespace, result.ConfigMaps[t.namespace]))
         }
-    },
+    }
+    if result.Secrets != nil {
+        if result.ConfigMaps != nil {
+            t.printer.Separator()
+        }
+        if allNamespaces {
+            exitOnPrintError(t.printer.PrintAllSecretsList(result.Secrets, maskSecrets))
+        } else {
+            exitOnPrintError(t.printer.PrintSecretsList(t.namespace, result.Secrets[t.namespace], maskSecrets))
+        }
+    }
+
+    return warnFailures(result.Failures), nil
 }
 
 func init() {
+    listCmd.Flags().StringVar(&kind, "kind", "configmap", "kind of resource to list: configmap, secret or all")
+    addSelectorFlags(listCmd)
+    listCmd.Flags().BoolVar(&maskSecrets, "mask-secrets", false, "hide Secret value sizes in the output")
+    addContextFlags(listCmd)
     rootCmd.AddCommand(listCmd)
 }
\ No newline at end of file
@@ -1,24 +1,39 @@
 package cmd
 
 import (
+    "context"
     "fmt"
     "os"
 
     "github.com/spf13/cobra"
-    "kmget/pkg/client"
     "kmget/pkg/configmap"
     "kmget/pkg/display"
+    "kmget/pkg/kmget"
 )
 
 var (
     configMapName string
+    pullAs        string
+    singleFile    bool
+    onCollision   string
+    concurrency   int
+    atomic        bool
+    forPod        string
+    forDeployment string
+    containerName string
+    archive       string
 )
 
+// stdoutOutput is the --output that streams a tar archive to stdout
+const stdoutOutput = "-"
+
 // pullCmd represents the pull command
 var pullCmd = &cobra.Command{
     Use:   "pull [CONFIGMAP_NAME]",
-    Short: "Pull ConfigMap data to local files",
-    Long: `Pull ConfigMap data to local files in the specified output directory.
+    Short: "Pull ConfigMap and Secret data to local files",
+    Long: `Pull ConfigMap (and optionally Secret) data to local files in the specified output directory.
+Secret values are written decoded. --save-as selects files or manifests; it was
+called --as before --as became kubectl's impersonation flag.
 
 Examples:
   # Pull a specific ConfigMap
@@ -27,47 +42,250 @@ Examples:
   # Pull all ConfigMaps from all namespaces
   kmget pull --all-namespaces --output ./all-configs
 
+  # Pull the ConfigMaps labelled app=payments from all namespaces
+  kmget pull --all-namespaces --selector app=payments --output ./payments
+
+  # Pull the ConfigMaps labelled app=payments from one namespace
+  kmget pull --namespace payments -l app=payments
+
+  # Back up all ConfigMaps as re-appliable manifests in a single file
+  kmget pull --all-namespaces --save-as manifest --single-file --output ./backup
+
   # Pull ConfigMap using positional argument
-  kmget pull my-config`,
+  kmget pull my-config
+
+  # Pull all ConfigMaps into one directory per ConfigMap
+  kmget pull --all-namespaces --layout ns/cm/key --output ./all-configs
+
+  # Back up the same namespace from several clusters into ./backup/<context>
+  kmget pull --contexts prod-eu,prod-us -n payments -l app=payments --output ./backup
+
+  # Keep ./config in sync with a ConfigMap and reload the app on every change
+  kmget pull my-config --watch --exec 'kill -HUP $(cat app.pid)' --output ./config
+
+  # Update ./config the way the kubelet updates a ConfigMap volume
+  kmget pull my-config --atomic --output ./config
+
+  # Reproduce the config files and ConfigMap environment of a Deployment's container
+  kmget pull --for-deployment web --container app --output ./web
+
+  # Pull a Secret and a ConfigMap that share a name
+  kmget pull my-app --kind all --output ./my-app
+
+  # Back up the whole cluster into one archive
+  kmget pull --all-namespaces --archive backup.tgz
+
+  # Stream a tar archive to another tool
+  kmget pull my-config -o - | tar -tv`,
     Args: func(cmd *cobra.Command, args []string) error {
-        if !allNamespaces && len(args) == 0 && configMapName == "" {
-            return fmt.Errorf("ConfigMap name is required when not using --all-namespaces flag")
+        selecting := labelSelector != "" || fieldSelector != ""
+        if forPod != "" || forDeployment != "" {
+            if forPod != "" && forDeployment != "" {
+                return fmt.Errorf("--for-pod and --for-deployment cannot be combined")
+            }
+            if allNamespaces || selecting || len(args) > 0 || configMapName != "" {
+                return fmt.Errorf("--for-pod and --for-deployment cannot be combined with a ConfigMap name, a selector or --all-namespaces")
+            }
+            return nil
+        }
+        if containerName != "" {
+            return fmt.Errorf("--container requires --for-pod or --for-deployment")
+        }
+        if !allNamespaces && !selecting && len(args) == 0 && configMapName == "" {
+            return fmt.Errorf("ConfigMap name is required when not using --all-namespaces or a selector")
+        }
+        if selecting && (len(args) > 0 || configMapName != "") {
+            return fmt.Errorf("a ConfigMap name cannot be combined with --selector or --field-selector")
         }
         return nil
     },
     Run: func(cmd *cobra.Command, args []string) {
+        printer := newPrinter()
+
         // Use positional argument if provided
         if len(args) > 0 {
             configMapName = args[0]
         }
 
-        k8sClient, err := client.NewClient(kubeconfig)
+        selected, err := resolveKind()
         if err != nil {
-            fmt.Fprintf(os.Stderr, "Error creating Kubernetes client: %v\n", err)
+            fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+            os.Exit(1)
+        }
+        withSecrets := selected != kmget.KindConfigMap
+        if withSecrets && pullAs == configmap.PullAsManifest {
+            fmt.Fprintf(os.Stderr, "Error: --save-as %s only supports ConfigMaps\n", configmap.PullAsManifest)
             os.Exit(1)
         }
 
-        ops := configmap.NewOperations(k8sClient.Clientset)
+        if atomic && selected == kmget.KindAll {
+            fmt.Fprintf(os.Stderr, "Error: --atomic writes one kind per output directory, use --kind configmap or --kind secret\n")
+            os.Exit(1)
+        }
+        if execHook != "" && !watch {
+            fmt.Fprintf(os.Stderr, "Error: --exec requires --watch\n")
+            os.Exit(1)
+        }
+        if archive != "" || outputDir == stdoutOutput {
+            if archive != "" && outputDir == stdoutOutput {
+                fmt.Fprintf(os.Stderr, "Error: --archive cannot be combined with -o -\n")
+                os.Exit(1)
+            }
+            if watch || atomic || len(contextNames) > 0 || allContexts {
+                fmt.Fprintf(os.Stderr, "Error: --archive and -o - cannot be combined with --watch, --atomic, --contexts or --all-contexts\n")
+                os.Exit(1)
+            }
+        }
+
+        if forPod != "" || forDeployment != "" {
+            if err := runPullForWorkload(cmd.Context(), printer, withSecrets); err != nil {
+                fmt.Fprintf(os.Stderr, "Error pulling workload configuration: %v\n", err)
+                os.Exit(1)
+            }
+            return
+        }
 
-        if allNamespaces {
-            results, err := ops.PullAllConfigMaps(outputDir)
-            if err != nil {
-                fmt.Fprintf(os.Stderr, "Error pulling ConfigMaps: %v\n", err)
+        layout := parseLayout()
+        if watch {
+            if withSecrets {
+                fmt.Fprintf(os.Stderr, "Error: --watch only supports ConfigMaps\n")
                 os.Exit(1)
             }
-            display.PrintPullAllResults(results)
-        } else {
-            result, err := ops.PullConfigMap(namespace, configMapName, outputDir)
-            if err != nil {
-                fmt.Fprintf(os.Stderr, "Error pulling ConfigMap: %v\n", err)
+            opts := configmap.PullOptions{
+                ListOptions: listOptions(cmd),
+                As:          pullAs,
+                SingleFile:  singleFile,
+                Layout:      layout,
+                OnCollision: onCollision,
+                Concurrency: concurrency,
+                Atomic:      atomic,
+            }
+            if err := runWatch(cmd.Context(), printer, opts); err != nil {
+                fmt.Fprintf(os.Stderr, "Error watching ConfigMaps: %v\n", err)
                 os.Exit(1)
             }
-            display.PrintPullResult(result)
+            return
         }
+
+        selectors := listOptions(cmd)
+        runTargets(cmd.Context(), printer, func(ctx context.Context, t *target) (bool, error) {
+            return runPull(ctx, t, kmget.PullOptions{
+                Name:            configMapName,
+                Namespace:       t.namespace,
+                AllNamespaces:   allNamespaces,
+                Kind:            selected,
+                OutputDir:       t.outputDir,
+                Archive:         archive,
+                LabelSelector:   selectors.LabelSelector,
+                FieldSelector:   selectors.FieldSelector,
+                ContinueOnError: selectors.ContinueOnError,
+                As:              pullAs,
+                SingleFile:      singleFile,
+                Layout:          layout,
+                OnCollision:     onCollision,
+                Concurrency:     concurrency,
+                Atomic:          atomic,
+            })
+        })
     },
 }
 
+// runPullForWorkload pulls what a container of --for-pod or --for-deployment sees
+func runPullForWorkload(ctx context.Context, printer *display.Printer, withSecrets bool) error {
+    if watch || len(contextNames) > 0 || allContexts || withSecrets || layout != "" {
+        return fmt.Errorf("--for-pod and --for-deployment cannot be combined with --watch, --contexts, --kind or --layout")
+    }
+
+    k8sClient := newClient()
+    ops := configmap.NewOperations(k8sClient.Clientset)
+    opts := configmap.PullOptions{
+        As:          pullAs,
+        OnCollision: onCollision,
+        Concurrency: concurrency,
+        Atomic:      atomic,
+    }
+    switch {
+    case archive != "":
+        sink, err := configmap.CreateArchive(archive)
+        if err != nil {
+            return err
+        }
+        defer sink.Close()
+        opts.Sink = sink
+    case outputDir == stdoutOutput:
+        sink := configmap.NewTarSink(os.Stdout, false)
+        defer sink.Close()
+        opts.Sink = sink
+    }
+
+    var result *configmap.PodPullResult
+    var err error
+    if forPod != "" {
+        result, err = ops.PullForPod(ctx, namespace, forPod, containerName, outputDir, opts)
+    } else {
+        result, err = ops.PullForDeployment(ctx, namespace, forDeployment, containerName, outputDir, opts)
+    }
+    if err != nil {
+        return err
+    }
+    exitOnPrintError(printer.PrintPodPullResult(result))
+    return nil
+}
+
+// runPull pulls from one cluster and reports whether some namespaces or
+// objects failed. Interrupted pulls still report what they wrote.
+func runPull(ctx context.Context, t *target, opts kmget.PullOptions) (partial bool, err error) {
+    if opts.OutputDir == stdoutOutput {
+        sink := configmap.NewTarSink(os.Stdout, false)
+        opts.Sink = sink
+        defer func() {
+            if closeErr := sink.Close(); err == nil {
+                err = closeErr
+            }
+        }()
+    }
+
+    result, err := t.client.Pull(ctx, opts)
+    partial = kmget.IsPartial(err)
+    if partial {
+        err = nil
+    }
+    if result == nil {
+        return false, err
+    }
+
+    if opts.AllNamespaces {
+        exitOnPrintError(t.printer.PrintPullAllResults(result))
+        return partial, err
+    }
+    if opts.Name == "" && len(result.Results) == 0 && err == nil {
+        fmt.Fprintf(os.Stderr, "No matching objects in namespace '%s'\n", t.namespace)
+    }
+    for i := range result.Results {
+        if i > 0 {
+            t.printer.Separator()
+        }
+        exitOnPrintError(t.printer.PrintPullResult(&result.Results[i]))
+    }
+    return partial, err
+}
+
 func init() {
     pullCmd.Flags().StringVarP(&configMapName, "configmap", "c", "", "name of the ConfigMap to pull")
+    addSelectorFlags(pullCmd)
+    pullCmd.Flags().StringVar(&kind, "kind", "configmap", "kind of resource to pull: configmap, secret or all")
+    pullCmd.Flags().StringVar(&pullAs, "save-as", configmap.PullAsFiles, "write one file per key (files) or cleaned ConfigMap manifests (manifest)")
+    pullCmd.Flags().BoolVar(&singleFile, "single-file", false, "with --save-as manifest, write all manifests to one multi-document "+configmap.ManifestStreamFile)
+    addLayoutFlag(pullCmd)
+    pullCmd.Flags().StringVar(&onCollision, "on-collision", configmap.CollisionFail, "when two keys map to the same file: fail or rename (prefix the ConfigMap name)")
+    addContextFlags(pullCmd)
+    pullCmd.Flags().BoolVar(&watch, "watch", false, "keep watching and rewrite the files whenever the ConfigMaps change, deleting files of removed keys")
+    pullCmd.Flags().StringVar(&execHook, "exec", "", "with --watch, shell command to run after every sync (gets KMGET_EVENT, KMGET_NAMESPACE, KMGET_CONFIGMAP and KMGET_OUTPUT)")
+    pullCmd.Flags().BoolVar(&atomic, "atomic", false, "write into a timestamped directory and swap a ..data symlink, like a ConfigMap volume; the output directory then holds only this pull")
+    pullCmd.Flags().StringVar(&forPod, "for-pod", "", "pull the ConfigMap volumes and environment a container of this pod sees")
+    pullCmd.Flags().StringVar(&forDeployment, "for-deployment", "", "pull the ConfigMap volumes and environment a container of this Deployment sees")
+    pullCmd.Flags().StringVar(&containerName, "container", "", "with --for-pod or --for-deployment, the container to pull for (default: the first container)")
+    pullCmd.Flags().IntVar(&concurrency, "concurrency", configmap.DefaultConcurrency, "number of files written in parallel")
+    pullCmd.Flags().StringVar(&archive, "archive", "", "write a .tar.gz, .tgz, .tar or .zip archive instead of files; use -o - to stream a tar to stdout")
     rootCmd.AddCommand(pullCmd)
 }
\ No newline at end of file
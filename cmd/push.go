@@ -0,0 +1,54 @@
+package cmd
+
+import (
+    "fmt"
+    "os"
+
+    "github.com/spf13/cobra"
+    "kmget/pkg/kmget"
+)
+
+// pushCmd represents the push command
+var pushCmd = &cobra.Command{
+    Use:   "push CONFIGMAP_NAME [DIRECTORY]",
+    Short: "Push local files into a ConfigMap",
+    Long: `Create or update a ConfigMap from the files in a local directory.
+
+Each regular file becomes a key of the ConfigMap. Files that are not valid
+UTF-8 are stored as binaryData. Hidden files and subdirectories are ignored.
+The ConfigMap is written with server-side apply, so keys previously pushed by
+kmget but no longer present locally are removed.
+
+Examples:
+  # Push the files in ./config to the ConfigMap my-config
+  kmget push my-config ./config --namespace default
+
+  # Push back a directory created by 'kmget pull'
+  kmget pull my-config -o ./config
+  kmget push my-config -o ./config`,
+    Args: cobra.RangeArgs(1, 2),
+    Run: func(cmd *cobra.Command, args []string) {
+        printer := newPrinter()
+
+        name := args[0]
+        dir := outputDir
+        if len(args) > 1 {
+            dir = args[1]
+        }
+
+        c := newLibraryClient()
+
+        result, err := c.Push(cmd.Context(), kmget.PushOptions{Name: name, Namespace: namespace, Dir: dir})
+        if result != nil {
+            exitOnPrintError(printer.PrintPushResult(result))
+        }
+        if err != nil {
+            fmt.Fprintf(os.Stderr, "Error pushing ConfigMap: %v\n", err)
+            os.Exit(1)
+        }
+    },
+}
+
+func init() {
+    rootCmd.AddCommand(pushCmd)
+}
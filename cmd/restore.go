@@ -0,0 +1,71 @@
+package cmd
+
+import (
+    "fmt"
+    "os"
+
+    "github.com/spf13/cobra"
+    "kmget/pkg/configmap"
+)
+
+var (
+    restoreDryRun     bool
+    restoreOnConflict string
+    namespaceMap      map[string]string
+)
+
+// restoreCmd represents the restore command
+var restoreCmd = &cobra.Command{
+    Use:   "restore [DIRECTORY]",
+    Short: "Restore ConfigMaps from a backup tree",
+    Long: `Recreate the ConfigMaps of a tree written by 'kmget pull --all-namespaces'.
+
+The tree is read through its ` + configmap.BackupMetadataFile + ` metadata file,
+which records the keys, labels and annotations of every ConfigMap. The
+directory defaults to the --output directory.
+
+Examples:
+  # Preview a restore without changing the cluster
+  kmget restore ./backup --dry-run
+
+  # Restore into a renamed namespace, replacing ConfigMaps that already exist
+  kmget restore ./backup --namespace-map payments=payments-v2 --on-conflict overwrite`,
+    Args: cobra.MaximumNArgs(1),
+    Run: func(cmd *cobra.Command, args []string) {
+        printer := newPrinter()
+
+        dir := outputDir
+        if len(args) > 0 {
+            dir = args[0]
+        }
+
+        k8sClient := newClient()
+
+        ops := configmap.NewOperations(k8sClient.Clientset)
+
+        results, err := ops.RestoreConfigMaps(cmd.Context(), dir, configmap.RestoreOptions{
+            DryRun:       restoreDryRun,
+            NamespaceMap: namespaceMap,
+            OnConflict:   restoreOnConflict,
+        })
+        if results != nil {
+            exitOnPrintError(printer.PrintRestoreResults(results))
+        }
+        if err != nil {
+            fmt.Fprintf(os.Stderr, "Error restoring ConfigMaps: %v\n", err)
+            os.Exit(1)
+        }
+        for _, result := range results {
+            if result.Error != nil {
+                os.Exit(1)
+            }
+        }
+    },
+}
+
+func init() {
+    restoreCmd.Flags().BoolVar(&restoreDryRun, "dry-run", false, "validate the restore with server-side dry run requests")
+    restoreCmd.Flags().StringToStringVar(&namespaceMap, "namespace-map", nil, "restore a namespace under another name (old=new, repeatable)")
+    restoreCmd.Flags().StringVar(&restoreOnConflict, "on-conflict", configmap.ConflictSkip, "what to do with existing ConfigMaps: skip, overwrite or fail")
+    rootCmd.AddCommand(restoreCmd)
+}
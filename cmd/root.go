@@ -1,12 +1,19 @@
 package cmd
 
 import (
+    "context"
     "fmt"
     "os"
+    "os/signal"
+    "syscall"
+    "time"
 
     "github.com/spf13/cobra"
     "github.com/spf13/viper"
     "kmget/pkg/client"
+    "kmget/pkg/configmap"
+    "kmget/pkg/display"
+    "kmget/pkg/kmget"
 )
 
 var (
@@ -15,8 +22,29 @@ var (
     namespace     string
     outputDir     string
     allNamespaces bool
+    kind          string
+    labelSelector string
+    fieldSelector string
+    outputFormat  string
+    layout        string
+    qps           float32
+    burst         int
+    keepGoing     bool
+
+    kubeContext    string
+    kubeCluster    string
+    kubeUser       string
+    impersonate    string
+    impersonateAs  []string
+    requestTimeout string
+    timeout        time.Duration
+    cancelTimeout  context.CancelFunc = func() {}
 )
 
+// exitPartialFailure is the exit code used when a bulk operation completed
+// but some namespaces or objects failed
+const exitPartialFailure = 3
+
 // rootCmd represents the base command when called without any subcommands
 var rootCmd = &cobra.Command{
     Use:   "kmget",
@@ -37,26 +65,196 @@ Examples:
 
   # Pull all ConfigMaps from all namespaces
   kmget pull --all-namespaces`,
+    PersistentPreRun: func(cmd *cobra.Command, args []string) {
+        if timeout > 0 {
+            var ctx context.Context
+            ctx, cancelTimeout = context.WithTimeout(cmd.Context(), timeout)
+            cmd.SetContext(ctx)
+        }
+    },
 }
 
 // Execute adds all child commands to the root command and sets flags appropriately.
+// Commands run with a context that is cancelled on SIGINT or SIGTERM, so that
+// requests in flight are aborted and bulk pulls stop after the files being
+// written. A second signal terminates right away.
 func Execute() error {
-    return rootCmd.Execute()
+    ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+    defer stop()
+    go func() {
+        <-ctx.Done()
+        stop()
+    }()
+    defer func() { cancelTimeout() }()
+
+    return rootCmd.ExecuteContext(ctx)
 }
 
 func init() {
     cobra.OnInitialize(initConfig)
 
     rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is $HOME/.kmget.yaml)")
-    rootCmd.PersistentFlags().StringVar(&kubeconfig, "kubeconfig", client.GetDefaultKubeconfig(), "path to kubeconfig file (respects KUBECONFIG env var)")
-    rootCmd.PersistentFlags().StringVarP(&namespace, "namespace", "n", "default", "Kubernetes namespace")
+    rootCmd.PersistentFlags().StringVar(&kubeconfig, "kubeconfig", "", "path to kubeconfig file (default: the KUBECONFIG list, then ~/.kube/config, then in-cluster)")
+    rootCmd.PersistentFlags().StringVar(&kubeContext, "context", "", "kubeconfig context to use")
+    rootCmd.PersistentFlags().StringVar(&kubeCluster, "cluster", "", "kubeconfig cluster to use")
+    rootCmd.PersistentFlags().StringVar(&kubeUser, "user", "", "kubeconfig user to use")
+    rootCmd.PersistentFlags().StringVar(&impersonate, "as", "", "username to impersonate")
+    rootCmd.PersistentFlags().StringArrayVar(&impersonateAs, "as-group", nil, "group to impersonate, can be repeated")
+    rootCmd.PersistentFlags().StringVar(&requestTimeout, "request-timeout", "0", "timeout for a single API request (e.g. 30s, 0 for none)")
+    rootCmd.PersistentFlags().DurationVar(&timeout, "timeout", 0, "give up on the whole command after this long, reporting what was completed (e.g. 5m, 0 for none)")
+    rootCmd.PersistentFlags().StringVarP(&namespace, "namespace", "n", "", "Kubernetes namespace (default: the context's namespace)")
     rootCmd.PersistentFlags().StringVarP(&outputDir, "output", "o", ".", "output directory for config files")
-    rootCmd.PersistentFlags().BoolVar(&allNamespaces, "all-namespaces", false, "operate on all namespaces")
+    rootCmd.PersistentFlags().BoolVarP(&allNamespaces, "all-namespaces", "A", false, "operate on all namespaces")
+    rootCmd.PersistentFlags().StringVar(&outputFormat, "format", display.FormatTable, "output format: json, yaml, table, wide, name or jsonpath=TEMPLATE")
+    rootCmd.PersistentFlags().Float32Var(&qps, "qps", 0, "maximum requests per second to the API server (0 for the client default of 5)")
+    rootCmd.PersistentFlags().IntVar(&burst, "burst", 0, "maximum burst of requests to the API server (0 for the client default of 10)")
 
     viper.BindPFlag("kubeconfig", rootCmd.PersistentFlags().Lookup("kubeconfig"))
+    viper.BindPFlag("context", rootCmd.PersistentFlags().Lookup("context"))
     viper.BindPFlag("namespace", rootCmd.PersistentFlags().Lookup("namespace"))
     viper.BindPFlag("output", rootCmd.PersistentFlags().Lookup("output"))
     viper.BindPFlag("all-namespaces", rootCmd.PersistentFlags().Lookup("all-namespaces"))
+    viper.BindPFlag("format", rootCmd.PersistentFlags().Lookup("format"))
+}
+
+// newClient connects to the cluster and resolves the namespace to operate
+// on when --namespace was not given
+func newClient() *client.Client {
+    k8sClient, err := client.NewClient(clientConfig())
+    if err != nil {
+        fmt.Fprintf(os.Stderr, "Error creating Kubernetes client: %v\n", err)
+        os.Exit(1)
+    }
+    namespace = k8sClient.Namespace
+    return k8sClient
+}
+
+// newLibraryClient connects through the kmget library like newClient
+func newLibraryClient() *kmget.Client {
+    c, err := kmget.New(libraryOptions())
+    if err != nil {
+        fmt.Fprintf(os.Stderr, "Error creating Kubernetes client: %v\n", err)
+        os.Exit(1)
+    }
+    namespace = c.Namespace()
+    return c
+}
+
+// libraryOptions builds the kmget client options from the flags
+func libraryOptions() kmget.Options {
+    return kmget.Options{
+        Kubeconfig:        kubeconfig,
+        Context:           kubeContext,
+        Cluster:           kubeCluster,
+        User:              kubeUser,
+        Namespace:         namespace,
+        Impersonate:       impersonate,
+        ImpersonateGroups: impersonateAs,
+        RequestTimeout:    requestTimeout,
+        QPS:               qps,
+        Burst:             burst,
+    }
+}
+
+// clientConfig builds the Kubernetes client configuration from the flags
+func clientConfig() *client.Config {
+    return &client.Config{
+        Kubeconfig:    kubeconfig,
+        Namespace:     namespace,
+        OutputDir:     outputDir,
+        AllNamespaces: allNamespaces,
+
+        Context:           kubeContext,
+        Cluster:           kubeCluster,
+        User:              kubeUser,
+        Impersonate:       impersonate,
+        ImpersonateGroups: impersonateAs,
+        RequestTimeout:    requestTimeout,
+
+        QPS:   qps,
+        Burst: burst,
+    }
+}
+
+// resolveKind translates the --kind flag into the kind to operate on
+func resolveKind() (string, error) {
+    parsed, err := kmget.ParseKind(kind)
+    if err != nil {
+        return "", fmt.Errorf("invalid --kind %q (must be one of: configmap, secret, all)", kind)
+    }
+    return parsed, nil
+}
+
+// newPrinter creates the printer selected by --format. With -o - stdout
+// carries the pulled files, so results go to stderr.
+func newPrinter() *display.Printer {
+    out := os.Stdout
+    if outputDir == stdoutOutput {
+        out = os.Stderr
+    }
+    printer, err := display.NewPrinter(outputFormat, out)
+    if err != nil {
+        fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+        os.Exit(1)
+    }
+    return printer
+}
+
+// exitOnPrintError aborts when results could not be rendered
+func exitOnPrintError(err error) {
+    if err != nil {
+        fmt.Fprintf(os.Stderr, "Error printing results: %v\n", err)
+        os.Exit(1)
+    }
+}
+
+// listOptions builds the list options from the selector flags.
+// --continue-on-error defaults to on with --all-namespaces.
+func listOptions(cmd *cobra.Command) configmap.ListOptions {
+    continueOnError := allNamespaces
+    if cmd.Flags().Changed("continue-on-error") {
+        continueOnError = keepGoing
+    }
+    return configmap.ListOptions{
+        LabelSelector:   labelSelector,
+        FieldSelector:   fieldSelector,
+        ContinueOnError: continueOnError,
+    }
+}
+
+// addSelectorFlags registers the label and field selector flags on cmd,
+// along with --continue-on-error for bulk operations
+func addSelectorFlags(cmd *cobra.Command) {
+    cmd.Flags().StringVarP(&labelSelector, "selector", "l", "", "label selector to filter on (e.g. app=payments)")
+    cmd.Flags().StringVar(&fieldSelector, "field-selector", "", "field selector to filter on (e.g. metadata.name=my-config)")
+    cmd.Flags().BoolVar(&keepGoing, "continue-on-error", false, "record failing namespaces and objects and carry on (default true with --all-namespaces)")
+}
+
+// warnFailures prints the failures of a bulk operation to stderr and
+// reports whether there were any
+func warnFailures(failures []configmap.Failure) bool {
+    for _, failure := range failures {
+        fmt.Fprintf(os.Stderr, "Warning: %v\n", failure.Error)
+    }
+    return len(failures) > 0
+}
+
+// parseLayout parses --layout, returning nil when the command default applies
+func parseLayout() *configmap.Layout {
+    if layout == "" {
+        return nil
+    }
+    parsed, err := configmap.ParseLayout(layout)
+    if err != nil {
+        fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+        os.Exit(1)
+    }
+    return parsed
+}
+
+// addLayoutFlag registers the --layout flag on cmd
+func addLayoutFlag(cmd *cobra.Command) {
+    cmd.Flags().StringVar(&layout, "layout", "", "file layout: ns/cm/key, ns/key, flat or a template such as {{.Namespace}}/{{.Name}}/{{.Key}} (default flat, ns/key with --all-namespaces)")
 }
 
 // initConfig reads in config file and ENV variables if set.
@@ -0,0 +1,101 @@
+package cmd
+
+import (
+    "fmt"
+    "os"
+    "strconv"
+    "strings"
+    "time"
+
+    "github.com/spf13/cobra"
+    metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+    "kmget/pkg/configmap"
+)
+
+var (
+    minAge        string
+    includeSystem bool
+)
+
+// unusedCmd represents the unused command
+var unusedCmd = &cobra.Command{
+    Use:   "unused",
+    Short: "Find ConfigMaps and keys that no workload uses",
+    Long: `Report the ConfigMaps that no Pod, Deployment, StatefulSet, DaemonSet, Job or
+CronJob references, followed by referenced ConfigMaps with keys that no workload
+reads.
+
+Well-known system ConfigMaps such as kube-root-ca.crt and the ConfigMaps in the
+kube-system, kube-public and kube-node-lease namespaces are left out unless
+--include-system is set. ConfigMaps read by operators, admission webhooks or
+other API clients are not visible to the scan, so check before deleting.
+
+Examples:
+  # Unused ConfigMaps in the current namespace
+  kmget unused
+
+  # Unused ConfigMaps older than 30 days across the cluster
+  kmget unused --all-namespaces --min-age 30d
+
+  # Only the names, e.g. for review before deletion
+  kmget unused -A --min-age 720h --format name`,
+    Run: func(cmd *cobra.Command, args []string) {
+        printer := newPrinter()
+
+        age, err := parseAge(minAge)
+        if err != nil {
+            fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+            os.Exit(1)
+        }
+
+        k8sClient := newClient()
+
+        ops := configmap.NewOperations(k8sClient.Clientset)
+
+        scanNamespace := namespace
+        if allNamespaces {
+            scanNamespace = metav1.NamespaceAll
+        }
+        unused, err := ops.FindUnused(cmd.Context(), scanNamespace, configmap.UnusedOptions{
+            ListOptions: configmap.ListOptions{
+                LabelSelector: labelSelector,
+                FieldSelector: fieldSelector,
+            },
+            MinAge:        age,
+            IncludeSystem: includeSystem,
+        })
+        if err != nil {
+            fmt.Fprintf(os.Stderr, "Error finding unused ConfigMaps: %v\n", err)
+            os.Exit(1)
+        }
+
+        exitOnPrintError(printer.PrintUnusedConfigMaps(unused))
+    },
+}
+
+// parseAge parses a duration such as 720h, or a number of days such as 30d
+func parseAge(age string) (time.Duration, error) {
+    if age == "" {
+        return 0, nil
+    }
+    if days, ok := strings.CutSuffix(age, "d"); ok {
+        n, err := strconv.Atoi(days)
+        if err != nil || n < 0 {
+            return 0, fmt.Errorf("invalid --min-age %q", age)
+        }
+        return time.Duration(n) * 24 * time.Hour, nil
+    }
+    duration, err := time.ParseDuration(age)
+    if err != nil || duration < 0 {
+        return 0, fmt.Errorf("invalid --min-age %q (e.g. 30d or 720h)", age)
+    }
+    return duration, nil
+}
+
+func init() {
+    unusedCmd.Flags().StringVarP(&labelSelector, "selector", "l", "", "label selector to filter on (e.g. app=payments)")
+    unusedCmd.Flags().StringVar(&fieldSelector, "field-selector", "", "field selector to filter on (e.g. metadata.name=my-config)")
+    unusedCmd.Flags().StringVar(&minAge, "min-age", "", "only report ConfigMaps created at least this long ago (e.g. 30d or 720h)")
+    unusedCmd.Flags().BoolVar(&includeSystem, "include-system", false, "also report kube-root-ca.crt and the ConfigMaps of the kube-* namespaces")
+    rootCmd.AddCommand(unusedCmd)
+}
@@ -0,0 +1,49 @@
+package cmd
+
+import (
+    "fmt"
+    "os"
+
+    "github.com/spf13/cobra"
+    "kmget/pkg/configmap"
+)
+
+// usageCmd represents the usage command
+var usageCmd = &cobra.Command{
+    Use:   "usage CONFIGMAP_NAME",
+    Short: "Show which workloads consume a ConfigMap",
+    Long: `Scan the Pods, Deployments, StatefulSets, DaemonSets, Jobs and CronJobs of the
+namespace for volumes, projected volumes, envFrom and configMapKeyRef entries that
+reference the ConfigMap, and report the consumers of each key.
+
+Pods and Jobs created by one of these workloads are reported as that workload;
+Pods of other controllers, such as a bare ReplicaSet, are reported as Pods. Keys
+that consumers reference but the ConfigMap lacks are marked as missing.
+
+Examples:
+  # Check who uses a ConfigMap before editing or deleting it
+  kmget usage app-config --namespace shop
+
+  # Machine-readable output
+  kmget usage app-config --format json`,
+    Args: cobra.ExactArgs(1),
+    Run: func(cmd *cobra.Command, args []string) {
+        printer := newPrinter()
+
+        k8sClient := newClient()
+
+        ops := configmap.NewOperations(k8sClient.Clientset)
+
+        result, err := ops.Usage(cmd.Context(), namespace, args[0])
+        if err != nil {
+            fmt.Fprintf(os.Stderr, "Error finding consumers: %v\n", err)
+            os.Exit(1)
+        }
+
+        exitOnPrintError(printer.PrintUsageResult(result))
+    },
+}
+
+func init() {
+    rootCmd.AddCommand(usageCmd)
+}
@@ -0,0 +1,58 @@
+package cmd
+
+import (
+    "context"
+    "fmt"
+    "os"
+    "os/exec"
+
+    metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+    "kmget/pkg/configmap"
+    "kmget/pkg/display"
+)
+
+var (
+    watch    bool
+    execHook string
+)
+
+// runWatch keeps the pulled ConfigMaps in sync until interrupted, running
+// --exec after every sync
+func runWatch(ctx context.Context, printer *display.Printer, opts configmap.PullOptions) error {
+    if len(contextNames) > 0 || allContexts {
+        return fmt.Errorf("--watch cannot be combined with --contexts or --all-contexts")
+    }
+
+    k8sClient := newClient()
+    ops := configmap.NewOperations(k8sClient.Clientset)
+
+    watchNamespace := namespace
+    if allNamespaces {
+        watchNamespace = metav1.NamespaceAll
+    }
+
+    return ops.WatchConfigMaps(ctx, watchNamespace, configMapName, outputDir, opts, func(event configmap.WatchEvent) {
+        exitOnPrintError(printer.PrintWatchEvent(&event))
+        if execHook != "" {
+            if err := runHook(ctx, event); err != nil {
+                fmt.Fprintf(os.Stderr, "Warning: hook failed: %v\n", err)
+            }
+        }
+    })
+}
+
+// runHook runs --exec through the shell. The synced ConfigMap is passed in
+// KMGET_* environment variables; the hook's output goes to stderr so it does
+// not mix with structured output.
+func runHook(ctx context.Context, event configmap.WatchEvent) error {
+    hook := exec.CommandContext(ctx, "sh", "-c", execHook)
+    hook.Env = append(os.Environ(),
+        "KMGET_EVENT="+event.Type,
+        "KMGET_NAMESPACE="+event.Result.Namespace,
+        "KMGET_CONFIGMAP="+event.Result.ConfigMapName,
+        "KMGET_OUTPUT="+outputDir,
+    )
+    hook.Stdout = os.Stderr
+    hook.Stderr = os.Stderr
+    return hook.Run()
+}
@@ -2,14 +2,18 @@ package client
 
 import (
     "context"
+    "encoding/json"
     "fmt"
-    "os"
     "path/filepath"
+    "sort"
+    "strings"
 
     "k8s.io/client-go/kubernetes"
+    "k8s.io/client-go/rest"
     "k8s.io/client-go/tools/clientcmd"
-    "k8s.io/client-go/util/homedir"
+    clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
     metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+    "k8s.io/apimachinery/pkg/version"
 )
 
 // Config holds the Kubernetes client configuration
@@ -20,98 +24,245 @@ type Config struct {
     OutputDir     string
     ListOnly      bool
     AllNamespaces bool
+
+    // Context, Cluster and User override the current kubeconfig context
+    // and the cluster and user it refers to
+    Context string
+    Cluster string
+    User    string
+
+    // Impersonate and ImpersonateGroups act as another user or groups
+    Impersonate       string
+    ImpersonateGroups []string
+
+    // RequestTimeout is a duration such as 30s, "0" or empty for none
+    RequestTimeout string
+
+    // QPS and Burst limit the request rate towards the API server. Zero
+    // keeps the client-go defaults (5 QPS, burst of 10).
+    QPS   float32
+    Burst int
 }
 
+// Sources of the namespace a Client operates on
+const (
+    NamespaceFromFlag           = "flag"
+    NamespaceFromContext        = "context"
+    NamespaceFromServiceAccount = "service account"
+    NamespaceFromDefault        = "default"
+)
+
 // Client wraps the Kubernetes clientset with additional functionality
 type Client struct {
-    Clientset *kubernetes.Clientset
-    Config    *Config
+    Clientset  kubernetes.Interface
+    Config     *Config
+    RESTConfig *rest.Config
+
+    // InCluster is set when no kubeconfig was found and the pod's service
+    // account is used instead
+    InCluster bool
+
+    // Namespace is Config.Namespace if set, else the namespace of the
+    // kubeconfig context or service account. NamespaceSource says which.
+    Namespace       string
+    NamespaceSource string
+
+    loadingRules *clientcmd.ClientConfigLoadingRules
+    clientConfig clientcmd.ClientConfig
 }
 
-// NewClient creates a new Kubernetes client
-func NewClient(kubeconfig string) (*Client, error) {
-    config, err := clientcmd.BuildConfigFromFlags("", kubeconfig)
+// NewClient creates a new Kubernetes client. Like kubectl it reads an
+// explicit kubeconfig, else the colon-separated KUBECONFIG list, else
+// ~/.kube/config, and falls back to in-cluster authentication when none of
+// them exist.
+func NewClient(cfg *Config) (*Client, error) {
+    loadingRules := clientcmd.NewDefaultClientConfigLoadingRules()
+    loadingRules.ExplicitPath = cfg.Kubeconfig
+
+    overrides := &clientcmd.ConfigOverrides{
+        CurrentContext: cfg.Context,
+        Context: clientcmdapi.Context{
+            Cluster:  cfg.Cluster,
+            AuthInfo: cfg.User,
+        },
+        AuthInfo: clientcmdapi.AuthInfo{
+            Impersonate:       cfg.Impersonate,
+            ImpersonateGroups: cfg.ImpersonateGroups,
+        },
+        Timeout: cfg.RequestTimeout,
+    }
+    clientConfig := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(loadingRules, overrides)
+
+    // Without any kubeconfig the deferred config switches to the in-cluster
+    // config on its own, applying the same overrides
+    config, err := clientConfig.ClientConfig()
+    if clientcmd.IsEmptyConfig(err) {
+        err = fmt.Errorf("no kubeconfig found and not running inside a cluster: %w", err)
+    }
     if err != nil {
         return nil, fmt.Errorf("failed to build config: %w", err)
     }
 
+    rawConfig, err := clientConfig.RawConfig()
+    if err != nil {
+        return nil, fmt.Errorf("failed to load kubeconfig: %w", err)
+    }
+    inCluster := len(rawConfig.Contexts) == 0 && len(rawConfig.Clusters) == 0
+    if cfg.QPS > 0 {
+        config.QPS = cfg.QPS
+    }
+    if cfg.Burst > 0 {
+        config.Burst = cfg.Burst
+    }
+
     clientset, err := kubernetes.NewForConfig(config)
     if err != nil {
         return nil, fmt.Errorf("failed to create clientset: %w", err)
     }
 
-    return &Client{
-        Clientset: clientset,
-    }, nil
+    c := &Client{
+        Clientset:    clientset,
+        Config:       cfg,
+        RESTConfig:   config,
+        InCluster:    inCluster,
+        loadingRules: loadingRules,
+        clientConfig: clientConfig,
+    }
+    if err := c.resolveNamespace(rawConfig); err != nil {
+        return nil, err
+    }
+    return c, nil
 }
 
-// GetDefaultKubeconfig returns the default kubeconfig path
-func GetDefaultKubeconfig() string {
-    if kubeconfigEnv := os.Getenv("KUBECONFIG"); kubeconfigEnv != "" {
-        return kubeconfigEnv
+// resolveNamespace picks the namespace the way kubectl does: the flag, else
+// the context's namespace, else the service account's namespace in a pod,
+// else "default"
+func (c *Client) resolveNamespace(rawConfig clientcmdapi.Config) error {
+    if c.Config.Namespace != "" {
+        c.Namespace, c.NamespaceSource = c.Config.Namespace, NamespaceFromFlag
+        return nil
     }
-    if home := homedir.HomeDir(); home != "" {
-        return filepath.Join(home, ".kube", "config")
+
+    namespace, _, err := c.clientConfig.Namespace()
+    if clientcmd.IsEmptyConfig(err) {
+        namespace, err = "", nil
+    }
+    if err != nil {
+        return fmt.Errorf("failed to resolve namespace: %w", err)
     }
-    return ""
+
+    contextName := rawConfig.CurrentContext
+    if c.Config.Context != "" {
+        contextName = c.Config.Context
+    }
+    context := rawConfig.Contexts[contextName]
+
+    switch {
+    case context != nil && context.Namespace != "":
+        c.NamespaceSource = NamespaceFromContext
+    case c.InCluster && namespace != "":
+        c.NamespaceSource = NamespaceFromServiceAccount
+    default:
+        namespace = "default"
+        c.NamespaceSource = NamespaceFromDefault
+    }
+    c.Namespace = namespace
+    return nil
+}
+
+// ListContexts returns the sorted names of the contexts in the kubeconfig
+// files cfg would load
+func ListContexts(cfg *Config) ([]string, error) {
+    loadingRules := clientcmd.NewDefaultClientConfigLoadingRules()
+    loadingRules.ExplicitPath = cfg.Kubeconfig
+
+    rawConfig, err := loadingRules.Load()
+    if err != nil {
+        return nil, fmt.Errorf("failed to load kubeconfig: %w", err)
+    }
+
+    names := make([]string, 0, len(rawConfig.Contexts))
+    for name := range rawConfig.Contexts {
+        names = append(names, name)
+    }
+    sort.Strings(names)
+    return names, nil
 }
 
 // ClusterInfo represents cluster information
 type ClusterInfo struct {
-    Context     string
-    Cluster     string
-    Endpoint    string
-    Namespace   string
-    Version     string
-    KubeconfigPath string
+    Context         string `json:"context"`
+    Cluster         string `json:"cluster"`
+    Endpoint        string `json:"endpoint"`
+    Namespace       string `json:"namespace"`
+    NamespaceSource string `json:"namespaceSource"`
+    Version         string `json:"version"`
+    KubeconfigPath  string `json:"kubeconfigPath"`
 }
 
-// GetClusterInfo retrieves cluster information
-func (c *Client) GetClusterInfo(kubeconfigPath string) (*ClusterInfo, error) {
-    version, err := c.Clientset.Discovery().ServerVersion()
+// GetClusterInfo retrieves cluster information for the context in use
+func (c *Client) GetClusterInfo(ctx context.Context) (*ClusterInfo, error) {
+    version, err := c.serverVersion(ctx)
     if err != nil {
         return nil, fmt.Errorf("failed to get server version: %w", err)
     }
 
-    loadingRules := &clientcmd.ClientConfigLoadingRules{ExplicitPath: kubeconfigPath}
-    configOverrides := &clientcmd.ConfigOverrides{}
-    kubeConfig := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(loadingRules, configOverrides)
+    info := &ClusterInfo{
+        Endpoint:        c.RESTConfig.Host,
+        Namespace:       c.Namespace,
+        NamespaceSource: c.NamespaceSource,
+        Version:         version.GitVersion,
+    }
+    if c.InCluster {
+        info.Context = "(in-cluster)"
+        return info, nil
+    }
 
-    rawConfig, err := kubeConfig.RawConfig()
+    rawConfig, err := c.clientConfig.RawConfig()
     if err != nil {
         return nil, fmt.Errorf("failed to load kubeconfig: %w", err)
     }
 
-    currentContext := rawConfig.CurrentContext
-    context, exists := rawConfig.Contexts[currentContext]
-    var clusterName, namespace string
-    if exists {
-        clusterName = context.Cluster
-        namespace = context.Namespace
-        if namespace == "" {
-            namespace = "default"
-        }
+    info.Context = rawConfig.CurrentContext
+    if c.Config.Context != "" {
+        info.Context = c.Config.Context
+    }
+    if context, exists := rawConfig.Contexts[info.Context]; exists {
+        info.Cluster = context.Cluster
+    }
+    if c.Config.Cluster != "" {
+        info.Cluster = c.Config.Cluster
     }
 
-    cluster, exists := rawConfig.Clusters[clusterName]
-    var endpoint string
-    if exists {
-        endpoint = cluster.Server
+    info.KubeconfigPath = c.loadingRules.ExplicitPath
+    if info.KubeconfigPath == "" {
+        info.KubeconfigPath = strings.Join(c.loadingRules.Precedence, string(filepath.ListSeparator))
     }
+    return info, nil
+}
 
-    return &ClusterInfo{
-        Context:        currentContext,
-        Cluster:        clusterName,
-        Endpoint:       endpoint,
-        Namespace:      namespace,
-        Version:        version.GitVersion,
-        KubeconfigPath: kubeconfigPath,
-    }, nil
+// serverVersion asks the API server for its version. Unlike
+// Discovery().ServerVersion() the request is cancelled along with ctx.
+func (c *Client) serverVersion(ctx context.Context) (*version.Info, error) {
+    restClient := c.Clientset.Discovery().RESTClient()
+    if restClient == nil {
+        // fake clientsets have no REST client
+        return c.Clientset.Discovery().ServerVersion()
+    }
+
+    body, err := restClient.Get().AbsPath("/version").Do(ctx).Raw()
+    if err != nil {
+        return nil, err
+    }
+    var info version.Info
+    if err := json.Unmarshal(body, &info); err != nil {
+        return nil, fmt.Errorf("unable to parse the server version: %w", err)
+    }
+    return &info, nil
 }
 
 // GetNamespaces returns all namespaces
-func (c *Client) GetNamespaces() ([]string, error) {
-    ctx := context.Background()
+func (c *Client) GetNamespaces(ctx context.Context) ([]string, error) {
     namespaces, err := c.Clientset.CoreV1().Namespaces().List(ctx, metav1.ListOptions{})
     if err != nil {
         return nil, fmt.Errorf("failed to list namespaces: %w", err)
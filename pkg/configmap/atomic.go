@@ -0,0 +1,152 @@
+package configmap
+
+import (
+    "errors"
+    "fmt"
+    "io/fs"
+    "os"
+    "path/filepath"
+    "strings"
+    "time"
+)
+
+// Names used by atomic pulls, matching the kubelet's atomic writer for
+// ConfigMap and Secret volumes
+const (
+    atomicDataDir     = "..data"
+    atomicDataDirTemp = "..data_tmp"
+)
+
+// newAtomicStage creates the timestamped directory an atomic pull writes to,
+// e.g. ..2024_05_01_10_00_00.123456789
+func newAtomicStage(root string) (string, error) {
+    if err := os.MkdirAll(root, 0755); err != nil {
+        return "", fmt.Errorf("failed to create output directory: %w", err)
+    }
+    stage, err := os.MkdirTemp(root, time.Now().UTC().Format("..2006_01_02_15_04_05."))
+    if err != nil {
+        return "", fmt.Errorf("failed to create staging directory: %w", err)
+    }
+    if err := os.Chmod(stage, 0755); err != nil {
+        os.RemoveAll(stage)
+        return "", fmt.Errorf("failed to create staging directory: %w", err)
+    }
+    return stage, nil
+}
+
+// publishAtomic makes stage the current contents of root the way the kubelet
+// does: the ..data symlink is pointed at stage with a single rename, every
+// top-level entry of stage is exposed as a symlink through ..data, and
+// entries and the directory of the previous pull are removed. Readers that
+// go through the symlinks see either the old or the new files, never a mix.
+func publishAtomic(root, stage string) error {
+    previous, visible, err := swapDataDir(root, stage)
+    if err != nil {
+        os.RemoveAll(stage)
+        return err
+    }
+
+    for name := range visible {
+        if err := linkVisible(root, name); err != nil {
+            return err
+        }
+    }
+    if err := removeStaleVisible(root, visible); err != nil {
+        return err
+    }
+
+    if previous != "" && previous != filepath.Base(stage) && strings.HasPrefix(previous, "..") && filepath.IsLocal(previous) {
+        if err := os.RemoveAll(filepath.Join(root, previous)); err != nil {
+            return fmt.Errorf("failed to remove previous data directory: %w", err)
+        }
+    }
+    return nil
+}
+
+// swapDataDir points ..data at stage and returns its previous target along
+// with the top-level entries of stage
+func swapDataDir(root, stage string) (string, map[string]bool, error) {
+    dataDir := filepath.Join(root, atomicDataDir)
+    previous, err := os.Readlink(dataDir)
+    if err != nil && !errors.Is(err, fs.ErrNotExist) {
+        return "", nil, fmt.Errorf("failed to read '%s': %w", dataDir, err)
+    }
+
+    entries, err := os.ReadDir(stage)
+    if err != nil {
+        return "", nil, fmt.Errorf("failed to read staging directory: %w", err)
+    }
+    visible := make(map[string]bool, len(entries))
+    for _, entry := range entries {
+        visible[entry.Name()] = true
+    }
+
+    tempLink := filepath.Join(root, atomicDataDirTemp)
+    if err := os.Remove(tempLink); err != nil && !errors.Is(err, fs.ErrNotExist) {
+        return "", nil, fmt.Errorf("failed to remove '%s': %w", tempLink, err)
+    }
+    if err := os.Symlink(filepath.Base(stage), tempLink); err != nil {
+        return "", nil, fmt.Errorf("failed to link '%s': %w", tempLink, err)
+    }
+    if err := os.Rename(tempLink, dataDir); err != nil {
+        os.Remove(tempLink)
+        return "", nil, fmt.Errorf("failed to swap '%s': %w", dataDir, err)
+    }
+    return previous, visible, nil
+}
+
+// linkVisible points root/name at ..data/name. A regular file left by a
+// non-atomic pull is replaced; a real directory is refused.
+func linkVisible(root, name string) error {
+    path := filepath.Join(root, name)
+    target := filepath.Join(atomicDataDir, name)
+
+    info, err := os.Lstat(path)
+    switch {
+    case errors.Is(err, fs.ErrNotExist):
+    case err != nil:
+        return fmt.Errorf("failed to inspect '%s': %w", path, err)
+    case info.Mode()&fs.ModeSymlink != 0:
+        if current, _ := os.Readlink(path); current == target {
+            return nil
+        }
+        if err := os.Remove(path); err != nil {
+            return fmt.Errorf("failed to replace '%s': %w", path, err)
+        }
+    case info.IsDir():
+        return fmt.Errorf("cannot link '%s': a directory is in the way", path)
+    default:
+        if err := os.Remove(path); err != nil {
+            return fmt.Errorf("failed to replace '%s': %w", path, err)
+        }
+    }
+
+    if err := os.Symlink(target, path); err != nil {
+        return fmt.Errorf("failed to link '%s': %w", path, err)
+    }
+    return nil
+}
+
+// removeStaleVisible removes the symlinks into ..data whose entries are no
+// longer part of the pulled data
+func removeStaleVisible(root string, visible map[string]bool) error {
+    entries, err := os.ReadDir(root)
+    if err != nil {
+        return fmt.Errorf("failed to read '%s': %w", root, err)
+    }
+    for _, entry := range entries {
+        name := entry.Name()
+        if visible[name] || entry.Type()&fs.ModeSymlink == 0 {
+            continue
+        }
+        path := filepath.Join(root, name)
+        target, err := os.Readlink(path)
+        if err != nil || !strings.HasPrefix(target, atomicDataDir+string(filepath.Separator)) {
+            continue
+        }
+        if err := os.Remove(path); err != nil {
+            return fmt.Errorf("failed to remove '%s': %w", path, err)
+        }
+    }
+    return nil
+}
@@ -0,0 +1,171 @@
+package configmap
+
+import (
+    "fmt"
+    "os"
+    "path/filepath"
+    "sort"
+
+    corev1 "k8s.io/api/core/v1"
+    "sigs.k8s.io/yaml"
+)
+
+// BackupMetadataFile is the sidecar written at the root of an all-namespace
+// pull. It records which files belong to which ConfigMap so the tree can be
+// restored.
+const BackupMetadataFile = ".kmget-backup.yaml"
+
+// BackupMetadata describes the ConfigMaps stored in a backup tree. Secrets
+// pulled into the same tree are only listed so that their files are known;
+// they are not restored.
+type BackupMetadata struct {
+    ConfigMaps []BackupEntry `json:"configMaps"`
+    Secrets    []BackupEntry `json:"secrets,omitempty"`
+}
+
+// BackupEntry describes a single ConfigMap of a backup tree
+type BackupEntry struct {
+    Namespace   string            `json:"namespace"`
+    Name        string            `json:"name"`
+    Labels      map[string]string `json:"labels,omitempty"`
+    Annotations map[string]string `json:"annotations,omitempty"`
+    Immutable   *bool             `json:"immutable,omitempty"`
+    Keys        []BackupKey       `json:"keys"`
+
+    // Incomplete is set when some keys could not be written. Keys then
+    // lists only part of the ConfigMap, so the entry cannot be restored.
+    Incomplete bool `json:"incomplete,omitempty"`
+}
+
+// BackupKey maps a ConfigMap key to its file, relative to the backup root
+type BackupKey struct {
+    Key    string `json:"key"`
+    Path   string `json:"path"`
+    Binary bool   `json:"binary,omitempty"`
+}
+
+// backupWriter collects metadata while a tree is pulled
+type backupWriter struct {
+    metadata BackupMetadata
+}
+
+// add records the keys of configMap that were saved successfully. A
+// ConfigMap with failed keys is marked incomplete.
+func (b *backupWriter) add(configMap *corev1.ConfigMap, result *PullConfigMapResult) {
+    entry := BackupEntry{
+        Namespace:   configMap.Namespace,
+        Name:        configMap.Name,
+        Labels:      configMap.Labels,
+        Annotations: restorableAnnotations(configMap.Annotations),
+        Immutable:   configMap.Immutable,
+    }
+    addSavedKeys(&entry, result)
+    b.metadata.ConfigMaps = append(b.metadata.ConfigMaps, entry)
+}
+
+// addSecret records where the keys of secret were saved
+func (b *backupWriter) addSecret(secret *corev1.Secret, result *PullConfigMapResult) {
+    entry := BackupEntry{Namespace: secret.Namespace, Name: secret.Name}
+    addSavedKeys(&entry, result)
+    b.metadata.Secrets = append(b.metadata.Secrets, entry)
+}
+
+// addSavedKeys records the keys of result that were saved successfully
+func addSavedKeys(entry *BackupEntry, result *PullConfigMapResult) {
+    entry.Keys = []BackupKey{}
+    entry.Incomplete = !writtenCompletely(result)
+    for _, saved := range result.SavedFiles {
+        if !saved.Success {
+            continue
+        }
+        entry.Keys = append(entry.Keys, BackupKey{
+            Key:    saved.Key,
+            Path:   filepath.ToSlash(saved.rel),
+            Binary: saved.Binary,
+        })
+    }
+}
+
+// BackupRecorder collects the backup metadata of several all-namespace
+// pulls into the same tree, such as one of ConfigMaps and one of Secrets,
+// so that they share a single BackupMetadataFile. The pulls record into it
+// instead of writing the file; the caller writes it once they are done.
+type BackupRecorder struct {
+    writer   backupWriter
+    recorded bool
+}
+
+// NewBackupRecorder creates an empty recorder
+func NewBackupRecorder() *BackupRecorder {
+    return &BackupRecorder{}
+}
+
+// Write stores the recorded metadata at the root of sink. It does nothing
+// when no pull got as far as recording its objects.
+func (b *BackupRecorder) Write(sink Sink) error {
+    if !b.recorded {
+        return nil
+    }
+    return b.writer.write(sink)
+}
+
+// writtenCompletely reports whether every file of result was written
+func writtenCompletely(result *PullConfigMapResult) bool {
+    for _, saved := range result.SavedFiles {
+        if !saved.Success {
+            return false
+        }
+    }
+    return true
+}
+
+// write stores the sidecar at the root of the tree sink writes
+func (b *backupWriter) write(sink Sink) error {
+    for _, entries := range [][]BackupEntry{b.metadata.ConfigMaps, b.metadata.Secrets} {
+        sort.Slice(entries, func(i, j int) bool {
+            if entries[i].Namespace != entries[j].Namespace {
+                return entries[i].Namespace < entries[j].Namespace
+            }
+            return entries[i].Name < entries[j].Name
+        })
+    }
+
+    data, err := yaml.Marshal(b.metadata)
+    if err != nil {
+        return fmt.Errorf("failed to encode backup metadata: %w", err)
+    }
+    if err := sink.WriteFile(BackupMetadataFile, data, 0644); err != nil {
+        return fmt.Errorf("failed to write backup metadata: %w", err)
+    }
+    return nil
+}
+
+// ReadBackupMetadata loads the sidecar of a backup tree
+func ReadBackupMetadata(root string) (*BackupMetadata, error) {
+    path := filepath.Join(root, BackupMetadataFile)
+    data, err := os.ReadFile(path)
+    if err != nil {
+        return nil, fmt.Errorf("failed to read backup metadata: %w", err)
+    }
+
+    var metadata BackupMetadata
+    if err := yaml.UnmarshalStrict(data, &metadata); err != nil {
+        return nil, fmt.Errorf("failed to parse backup metadata '%s': %w", path, err)
+    }
+    return &metadata, nil
+}
+
+// restorableAnnotations drops annotations that only describe live state
+func restorableAnnotations(annotations map[string]string) map[string]string {
+    var kept map[string]string
+    for key, value := range annotations {
+        if isStrippedAnnotation(key) {
+            continue
+        }
+        if kept == nil {
+            kept = make(map[string]string)
+        }
+        kept[key] = value
+    }
+    return kept
+}
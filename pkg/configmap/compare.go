@@ -0,0 +1,284 @@
+package configmap
+
+import (
+    "bytes"
+    "context"
+    "fmt"
+    "path"
+    "sort"
+    "strings"
+    "unicode/utf8"
+
+    corev1 "k8s.io/api/core/v1"
+    apierrors "k8s.io/apimachinery/pkg/api/errors"
+    "kmget/pkg/diff"
+)
+
+// Key statuses reported when comparing two ConfigMaps
+const (
+    KeyIdentical    = "identical"
+    KeyMissingLeft  = "missing-left"
+    KeyMissingRight = "missing-right"
+)
+
+// CompareSource identifies a ConfigMap, or every ConfigMap of a namespace
+// when Name is "*". An empty Context means the current context and an empty
+// Namespace the resolved default namespace.
+type CompareSource struct {
+    Context   string `json:"context,omitempty"`
+    Namespace string `json:"namespace"`
+    Name      string `json:"name"`
+}
+
+// ParseCompareSource parses [[context/]namespace/]name, where name may be "*"
+// to select a whole namespace
+func ParseCompareSource(source string) (CompareSource, error) {
+    parts := strings.Split(source, "/")
+    for _, part := range parts {
+        if part == "" {
+            return CompareSource{}, fmt.Errorf("invalid source %q (expected [[context/]namespace/]name)", source)
+        }
+    }
+
+    switch len(parts) {
+    case 1:
+        return CompareSource{Name: parts[0]}, nil
+    case 2:
+        return CompareSource{Namespace: parts[0], Name: parts[1]}, nil
+    case 3:
+        return CompareSource{Context: parts[0], Namespace: parts[1], Name: parts[2]}, nil
+    default:
+        return CompareSource{}, fmt.Errorf("invalid source %q (expected [[context/]namespace/]name)", source)
+    }
+}
+
+// WholeNamespace reports whether the source selects every ConfigMap of its namespace
+func (s CompareSource) WholeNamespace() bool {
+    return s.Name == "*"
+}
+
+// String returns the source as context/namespace/name
+func (s CompareSource) String() string {
+    if s.Context == "" {
+        return s.Namespace + "/" + s.Name
+    }
+    return s.Context + "/" + s.Namespace + "/" + s.Name
+}
+
+// KeyComparison describes how a key differs between two ConfigMaps
+type KeyComparison struct {
+    Key       string `json:"key"`
+    Status    string `json:"status"`
+    Binary    bool   `json:"binary"`
+    Diff      string `json:"diff,omitempty"`
+    LeftHash  string `json:"leftHash,omitempty"`
+    RightHash string `json:"rightHash,omitempty"`
+}
+
+// CompareResult is the comparison of one ConfigMap between two sources
+type CompareResult struct {
+    ConfigMapName string          `json:"name"`
+    Left          CompareSource   `json:"left"`
+    Right         CompareSource   `json:"right"`
+    MissingLeft   bool            `json:"missingLeft,omitempty"`
+    MissingRight  bool            `json:"missingRight,omitempty"`
+    Keys          []KeyComparison `json:"keys"`
+}
+
+// HasDifferences reports whether any key is not identical on both sides
+func (r *CompareResult) HasDifferences() bool {
+    if r.MissingLeft || r.MissingRight {
+        return true
+    }
+    for _, key := range r.Keys {
+        if key.Status != KeyIdentical {
+            return true
+        }
+    }
+    return false
+}
+
+// CompareOptions controls a comparison
+type CompareOptions struct {
+    // IgnoreKeys are glob patterns (path.Match syntax) of keys that are
+    // expected to differ and left out of the comparison
+    IgnoreKeys []string
+}
+
+// ignored reports whether key matches one of the ignore patterns
+func (c CompareOptions) ignored(key string) bool {
+    for _, pattern := range c.IgnoreKeys {
+        if matched, _ := path.Match(pattern, key); matched {
+            return true
+        }
+    }
+    return false
+}
+
+// validate checks the ignore patterns
+func (c CompareOptions) validate() error {
+    for _, pattern := range c.IgnoreKeys {
+        if _, err := path.Match(pattern, ""); err != nil {
+            return fmt.Errorf("invalid ignore pattern %q: %w", pattern, err)
+        }
+    }
+    return nil
+}
+
+// Compare compares the ConfigMaps selected by left and right, which may
+// live in different clusters. Both sources must select a single ConfigMap
+// or both a whole namespace; namespaces are matched by ConfigMap name.
+func Compare(ctx context.Context, leftOps *Operations, left CompareSource, rightOps *Operations, right CompareSource, opts CompareOptions) ([]CompareResult, error) {
+    if err := opts.validate(); err != nil {
+        return nil, err
+    }
+    if left.WholeNamespace() != right.WholeNamespace() {
+        return nil, fmt.Errorf("cannot compare a single ConfigMap with a whole namespace")
+    }
+
+    if !left.WholeNamespace() {
+        leftConfigMap, err := getForCompare(ctx, leftOps, left)
+        if err != nil {
+            return nil, err
+        }
+        rightConfigMap, err := getForCompare(ctx, rightOps, right)
+        if err != nil {
+            return nil, err
+        }
+        result := compareConfigMaps(left, leftConfigMap, right, rightConfigMap, opts)
+        if left.Name == right.Name {
+            result.ConfigMapName = left.Name
+        } else {
+            result.ConfigMapName = left.Name + " ↔ " + right.Name
+        }
+        return []CompareResult{result}, nil
+    }
+
+    leftConfigMaps, err := listForCompare(ctx, leftOps, left)
+    if err != nil {
+        return nil, err
+    }
+    rightConfigMaps, err := listForCompare(ctx, rightOps, right)
+    if err != nil {
+        return nil, err
+    }
+
+    names := make([]string, 0, len(leftConfigMaps)+len(rightConfigMaps))
+    for name := range leftConfigMaps {
+        names = append(names, name)
+    }
+    for name := range rightConfigMaps {
+        if _, ok := leftConfigMaps[name]; !ok {
+            names = append(names, name)
+        }
+    }
+    sort.Strings(names)
+
+    results := make([]CompareResult, 0, len(names))
+    for _, name := range names {
+        leftSide, rightSide := left, right
+        leftSide.Name, rightSide.Name = name, name
+        result := compareConfigMaps(leftSide, leftConfigMaps[name], rightSide, rightConfigMaps[name], opts)
+        result.ConfigMapName = name
+        results = append(results, result)
+    }
+    return results, nil
+}
+
+// getForCompare fetches a ConfigMap, returning nil when it does not exist
+func getForCompare(ctx context.Context, ops *Operations, source CompareSource) (*corev1.ConfigMap, error) {
+    configMap, err := ops.GetConfigMap(ctx, source.Namespace, source.Name)
+    if apierrors.IsNotFound(err) {
+        return nil, nil
+    }
+    return configMap, err
+}
+
+// listForCompare fetches the ConfigMaps of a namespace by name
+func listForCompare(ctx context.Context, ops *Operations, source CompareSource) (map[string]*corev1.ConfigMap, error) {
+    configMaps, err := ops.listConfigMaps(ctx, source.Namespace, ListOptions{})
+    if err != nil {
+        return nil, fmt.Errorf("failed to list ConfigMaps in namespace '%s': %w", source.Namespace, err)
+    }
+
+    byName := make(map[string]*corev1.ConfigMap, len(configMaps))
+    for i := range configMaps {
+        byName[configMaps[i].Name] = &configMaps[i]
+    }
+    return byName, nil
+}
+
+// compareConfigMaps compares two ConfigMaps key by key. A nil ConfigMap does
+// not exist and is compared as if it were empty.
+func compareConfigMaps(left CompareSource, leftConfigMap *corev1.ConfigMap, right CompareSource, rightConfigMap *corev1.ConfigMap, opts CompareOptions) CompareResult {
+    result := CompareResult{
+        Left:         left,
+        Right:        right,
+        MissingLeft:  leftConfigMap == nil,
+        MissingRight: rightConfigMap == nil,
+        Keys:         []KeyComparison{},
+    }
+    if leftConfigMap == nil {
+        leftConfigMap = &corev1.ConfigMap{}
+    }
+    if rightConfigMap == nil {
+        rightConfigMap = &corev1.ConfigMap{}
+    }
+
+    keys := configMapKeys(leftConfigMap)
+    for _, key := range configMapKeys(rightConfigMap) {
+        if !hasKey(leftConfigMap, key) {
+            keys = append(keys, key)
+        }
+    }
+    sort.Strings(keys)
+
+    for _, key := range keys {
+        if opts.ignored(key) {
+            continue
+        }
+
+        leftValue, inLeft := liveBytes(leftConfigMap, key)
+        rightValue, inRight := liveBytes(rightConfigMap, key)
+        _, leftBinary := leftConfigMap.BinaryData[key]
+        _, rightBinary := rightConfigMap.BinaryData[key]
+
+        comparison := KeyComparison{
+            Key:    key,
+            Binary: leftBinary || rightBinary || !utf8.Valid(leftValue) || !utf8.Valid(rightValue),
+        }
+        switch {
+        case !inLeft:
+            comparison.Status = KeyMissingLeft
+        case !inRight:
+            comparison.Status = KeyMissingRight
+        case bytes.Equal(leftValue, rightValue):
+            comparison.Status = KeyIdentical
+            result.Keys = append(result.Keys, comparison)
+            continue
+        default:
+            comparison.Status = KeyChanged
+        }
+
+        if comparison.Binary {
+            if inLeft {
+                comparison.LeftHash = hashBytes(leftValue)
+            }
+            if inRight {
+                comparison.RightHash = hashBytes(rightValue)
+            }
+        } else {
+            fromName, toName := "/dev/null", "/dev/null"
+            if inLeft {
+                fromName = left.String() + "/" + key
+            }
+            if inRight {
+                toName = right.String() + "/" + key
+            }
+            comparison.Diff = diff.Unified(fromName, toName, string(leftValue), string(rightValue), diffContext)
+        }
+        result.Keys = append(result.Keys, comparison)
+    }
+
+    return result
+}
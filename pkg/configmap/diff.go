@@ -0,0 +1,422 @@
+package configmap
+
+import (
+    "bytes"
+    "context"
+    "crypto/sha256"
+    "encoding/hex"
+    "errors"
+    "fmt"
+    "io/fs"
+    "os"
+    "path/filepath"
+    "sort"
+    "strings"
+    "unicode/utf8"
+
+    corev1 "k8s.io/api/core/v1"
+    apierrors "k8s.io/apimachinery/pkg/api/errors"
+    "kmget/pkg/diff"
+)
+
+// diffContext is the number of unchanged lines shown around each change
+const diffContext = 3
+
+// KeyDiff describes how a single key differs between a ConfigMap and disk.
+// Added keys exist only on disk, removed keys exist only in the ConfigMap.
+type KeyDiff struct {
+    Key       string `json:"key"`
+    Path      string `json:"path"`
+    Status    string `json:"status"`
+    Binary    bool   `json:"binary"`
+    Diff      string `json:"diff,omitempty"`
+    LiveHash  string `json:"liveHash,omitempty"`
+    LocalHash string `json:"localHash,omitempty"`
+}
+
+// DiffResult represents the drift between a ConfigMap and a local directory.
+// ConfigMapName is empty for files that no ConfigMap accounts for.
+type DiffResult struct {
+    ConfigMapName string    `json:"name,omitempty"`
+    Namespace     string    `json:"namespace"`
+    Dir           string    `json:"dir"`
+    Missing       bool      `json:"missing,omitempty"`
+    Changes       []KeyDiff `json:"changes"`
+}
+
+// HasDrift reports whether any key differs
+func (r *DiffResult) HasDrift() bool {
+    return len(r.Changes) > 0
+}
+
+// DiffConfigMap compares the files PullConfigMap would write for a ConfigMap
+// into dir, laid out with layout (LayoutFlat when nil), against what is on
+// disk. A ConfigMap that does not exist is treated as empty and flagged as
+// Missing.
+func (o *Operations) DiffConfigMap(ctx context.Context, namespace, name, dir string, layout *Layout) (*DiffResult, error) {
+    if layout == nil {
+        layout = mustParseLayout(LayoutFlat)
+    }
+
+    result := &DiffResult{
+        ConfigMapName: name,
+        Namespace:     namespace,
+        Dir:           dir,
+    }
+
+    live := &corev1.ConfigMap{}
+    live.Name, live.Namespace = name, namespace
+    configMap, err := o.GetConfigMap(ctx, namespace, name)
+    if apierrors.IsNotFound(err) {
+        result.Missing = true
+    } else if err != nil {
+        return nil, err
+    } else {
+        live = configMap
+    }
+
+    files, err := layoutFiles(live, dir, layout)
+    if err != nil {
+        return nil, err
+    }
+
+    // Files next to the ConfigMap's own files are added keys when the layout
+    // would have written a key of that name to exactly that path
+    keyDir, err := keyDirectory(live, dir, layout)
+    if err != nil {
+        return nil, err
+    }
+    local, err := localFiles(keyDir)
+    if err != nil && !errors.Is(err, os.ErrNotExist) {
+        return nil, err
+    }
+    for _, fileName := range local {
+        if hasKey(live, fileName) {
+            continue
+        }
+        path, err := layoutPath(live, fileName, dir, layout)
+        if err == nil && path == filepath.Join(keyDir, fileName) {
+            files = append(files, keyFile{key: fileName, path: path})
+        }
+    }
+
+    changes, err := diffKeys(live, files)
+    if err != nil {
+        return nil, err
+    }
+    if len(files) > 0 {
+        result.Dir = filepath.Dir(files[0].path)
+    }
+    result.Changes = changes
+    return result, nil
+}
+
+// DiffAllConfigMaps compares every ConfigMap against a tree written by
+// PullAllConfigMaps with layout (LayoutNamespaceKey when nil). Keys are
+// looked for where the tree's backup metadata recorded them, so files that
+// pull renamed on a collision are found; keys it does not record get the
+// path pull would give them now. Files of Secrets recorded in the metadata
+// are left alone. Files in the tree that belong to no ConfigMap are
+// reported as added, grouped by directory in results with an empty
+// ConfigMapName. Namespaces that cannot be listed are returned as failures.
+func (o *Operations) DiffAllConfigMaps(ctx context.Context, outputDir string, layout *Layout) ([]DiffResult, []Failure, error) {
+    if layout == nil {
+        layout = mustParseLayout(LayoutNamespaceKey)
+    }
+
+    configMaps, failures, err := listAllNamespaces(ctx, o, KindConfigMap, ListOptions{ContinueOnError: true}, o.listConfigMaps)
+    if err != nil {
+        return nil, failures, err
+    }
+    sort.Slice(configMaps, func(i, j int) bool {
+        if configMaps[i].Namespace != configMaps[j].Namespace {
+            return configMaps[i].Namespace < configMaps[j].Namespace
+        }
+        return configMaps[i].Name < configMaps[j].Name
+    })
+
+    paths, err := newTreePaths(outputDir)
+    if err != nil {
+        return nil, failures, err
+    }
+
+    var results []DiffResult
+    for i := range configMaps {
+        live := &configMaps[i]
+        if len(live.Data) == 0 && len(live.BinaryData) == 0 {
+            continue // PullAllConfigMaps skips empty ConfigMaps
+        }
+
+        files, err := paths.files(live, layout)
+        if err != nil {
+            return nil, failures, err
+        }
+        changes, err := diffKeys(live, files)
+        if err != nil {
+            return nil, failures, err
+        }
+        results = append(results, DiffResult{
+            ConfigMapName: live.Name,
+            Namespace:     live.Namespace,
+            Dir:           filepath.Dir(files[0].path),
+            Changes:       changes,
+        })
+    }
+
+    untracked, err := untrackedFiles(outputDir, paths.claimed)
+    if err != nil {
+        return nil, failures, err
+    }
+    for _, dir := range sortedDirs(untracked) {
+        var files []keyFile
+        for _, path := range untracked[dir] {
+            files = append(files, keyFile{key: filepath.Base(path), path: path})
+        }
+        changes, err := diffKeys(&corev1.ConfigMap{}, files)
+        if err != nil {
+            return nil, failures, err
+        }
+        results = append(results, DiffResult{Dir: dir, Changes: changes})
+    }
+
+    return results, failures, nil
+}
+
+// treePaths resolves the files of keys in a tree written by
+// PullAllConfigMaps the way the pull did
+type treePaths struct {
+    root     string
+    tracker  *PathTracker
+    recorded map[string]map[string]string // namespace/name -> key -> path
+    claimed  map[string]bool
+}
+
+// newTreePaths reads the backup metadata of root, if there is any, and
+// reserves the paths it records
+func newTreePaths(root string) (*treePaths, error) {
+    t := &treePaths{
+        root:     root,
+        tracker:  NewPathTracker(),
+        recorded: make(map[string]map[string]string),
+        claimed:  make(map[string]bool),
+    }
+    t.tracker.claim(BackupMetadataFile, backupMetadataOwner, "", CollisionFail)
+
+    metadata, err := ReadBackupMetadata(root)
+    if errors.Is(err, fs.ErrNotExist) {
+        return t, nil
+    } else if err != nil {
+        return nil, err
+    }
+
+    for _, entry := range metadata.ConfigMaps {
+        keys := make(map[string]string)
+        for _, key := range entry.Keys {
+            rel := filepath.FromSlash(key.Path)
+            t.tracker.claim(rel, keyOwner(KindConfigMap, entry.Namespace, entry.Name, key.Key), "", CollisionFail)
+            keys[key.Key] = rel
+        }
+        t.recorded[entry.Namespace+"/"+entry.Name] = keys
+    }
+    // Secret files are not compared, but they are not untracked either
+    for _, entry := range metadata.Secrets {
+        for _, key := range entry.Keys {
+            rel := filepath.FromSlash(key.Path)
+            t.tracker.claim(rel, keyOwner(KindSecret, entry.Namespace, entry.Name, key.Key), "", CollisionFail)
+            if path, err := resolvePath(root, rel); err == nil {
+                t.claimed[path] = true
+            }
+        }
+    }
+    return t, nil
+}
+
+// files returns the paths of the keys of cm, and of the keys recorded for
+// it that are gone now. Keys without a recorded path get their layout path,
+// renamed like pull does when another key holds it.
+func (t *treePaths) files(cm *corev1.ConfigMap, layout *Layout) ([]keyFile, error) {
+    recorded := t.recorded[cm.Namespace+"/"+cm.Name]
+    keys := configMapKeys(cm)
+    for key := range recorded {
+        if _, ok := liveBytes(cm, key); !ok {
+            keys = append(keys, key)
+        }
+    }
+
+    var files []keyFile
+    for _, key := range keys {
+        rel, ok := recorded[key]
+        if !ok {
+            var err error
+            rel, err = layout.Path(LayoutFields{
+                Namespace: cm.Namespace,
+                Name:      cm.Name,
+                Kind:      KindConfigMap,
+                Key:       key,
+            })
+            if err != nil {
+                return nil, err
+            }
+            rel, err = t.tracker.claim(rel, keyOwner(KindConfigMap, cm.Namespace, cm.Name, key), cm.Name, CollisionRename)
+            if err != nil {
+                return nil, err
+            }
+        }
+        path, err := resolvePath(t.root, rel)
+        if err != nil {
+            return nil, err
+        }
+        t.claimed[path] = true
+        files = append(files, keyFile{key: key, path: path})
+    }
+    return files, nil
+}
+
+// keyFile is a key together with the path the layout gives it
+type keyFile struct {
+    key  string
+    path string
+}
+
+// layoutPath returns the path of key of cm below root
+func layoutPath(cm *corev1.ConfigMap, key, root string, layout *Layout) (string, error) {
+    rel, err := layout.Path(LayoutFields{
+        Namespace: cm.Namespace,
+        Name:      cm.Name,
+        Kind:      KindConfigMap,
+        Key:       key,
+    })
+    if err != nil {
+        return "", err
+    }
+    return resolvePath(root, rel)
+}
+
+// layoutFiles returns the paths of every key of cm below root
+func layoutFiles(cm *corev1.ConfigMap, root string, layout *Layout) ([]keyFile, error) {
+    var files []keyFile
+    for _, key := range configMapKeys(cm) {
+        path, err := layoutPath(cm, key, root, layout)
+        if err != nil {
+            return nil, err
+        }
+        files = append(files, keyFile{key: key, path: path})
+    }
+    return files, nil
+}
+
+// keyDirectory returns the directory the layout writes the keys of cm into
+func keyDirectory(cm *corev1.ConfigMap, root string, layout *Layout) (string, error) {
+    path, err := layoutPath(cm, "key", root, layout)
+    if err != nil {
+        return "", err
+    }
+    return filepath.Dir(path), nil
+}
+
+// untrackedFiles walks root and returns the non-hidden files that are not
+// claimed, grouped by directory. Hidden directories are skipped.
+func untrackedFiles(root string, claimed map[string]bool) (map[string][]string, error) {
+    untracked := make(map[string][]string)
+    err := filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+        if err != nil {
+            if errors.Is(err, fs.ErrNotExist) && path == root {
+                return fs.SkipAll
+            }
+            return err
+        }
+        hidden := path != root && strings.HasPrefix(entry.Name(), ".")
+        if entry.IsDir() {
+            if hidden {
+                return fs.SkipDir
+            }
+            return nil
+        }
+        if hidden || !entry.Type().IsRegular() || claimed[path] {
+            return nil
+        }
+        dir := filepath.Dir(path)
+        untracked[dir] = append(untracked[dir], path)
+        return nil
+    })
+    if err != nil {
+        return nil, fmt.Errorf("failed to walk '%s': %w", root, err)
+    }
+    return untracked, nil
+}
+
+func sortedDirs(files map[string][]string) []string {
+    dirs := make([]string, 0, len(files))
+    for dir := range files {
+        dirs = append(dirs, dir)
+    }
+    sort.Strings(dirs)
+    return dirs
+}
+
+// diffKeys compares the keys of live with the files they map to. Keys that
+// are not in live are local additions.
+func diffKeys(live *corev1.ConfigMap, files []keyFile) ([]KeyDiff, error) {
+    var changes []KeyDiff
+    for _, file := range files {
+        keyDiff := KeyDiff{Key: file.key, Path: file.path}
+
+        liveValue, inLive := liveBytes(live, file.key)
+        _, liveBinary := live.BinaryData[file.key]
+
+        localValue, err := os.ReadFile(file.path)
+        inLocal := err == nil
+        if err != nil && !errors.Is(err, os.ErrNotExist) {
+            return nil, fmt.Errorf("failed to read '%s': %w", file.path, err)
+        }
+
+        switch {
+        case inLive && !inLocal:
+            keyDiff.Status = KeyRemoved
+        case !inLive && inLocal:
+            keyDiff.Status = KeyAdded
+        case !inLive && !inLocal, bytes.Equal(liveValue, localValue):
+            continue
+        default:
+            keyDiff.Status = KeyChanged
+        }
+
+        keyDiff.Binary = liveBinary || (inLocal && !utf8.Valid(localValue))
+        if keyDiff.Binary {
+            if inLive {
+                keyDiff.LiveHash = hashBytes(liveValue)
+            }
+            if inLocal {
+                keyDiff.LocalHash = hashBytes(localValue)
+            }
+        } else {
+            fromName, toName := "/dev/null", "/dev/null"
+            if inLive {
+                fromName = fmt.Sprintf("live/%s/%s/%s", live.Namespace, live.Name, file.key)
+            }
+            if inLocal {
+                toName = file.path
+            }
+            keyDiff.Diff = diff.Unified(fromName, toName, string(liveValue), string(localValue), diffContext)
+        }
+
+        changes = append(changes, keyDiff)
+    }
+
+    sort.Slice(changes, func(i, j int) bool { return changes[i].Key < changes[j].Key })
+    return changes, nil
+}
+
+// liveBytes returns the value of key from Data or BinaryData
+func liveBytes(cm *corev1.ConfigMap, key string) ([]byte, bool) {
+    if value, ok := cm.Data[key]; ok {
+        return []byte(value), true
+    }
+    value, ok := cm.BinaryData[key]
+    return value, ok
+}
+
+func hashBytes(value []byte) string {
+    sum := sha256.Sum256(value)
+    return hex.EncodeToString(sum[:])
+}
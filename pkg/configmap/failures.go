@@ -0,0 +1,109 @@
+package configmap
+
+import (
+    "context"
+    "fmt"
+
+    apierrors "k8s.io/apimachinery/pkg/api/errors"
+    metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Failure records a namespace or object that could not be processed during a
+// bulk operation run with ContinueOnError
+type Failure struct {
+    Kind      string `json:"kind"`
+    Namespace string `json:"namespace"`
+    Name      string `json:"name,omitempty"` // empty for a whole namespace
+    Error     error  `json:"-"`
+}
+
+// PullAllResult is the outcome of a pull across all namespaces
+type PullAllResult struct {
+    Results  []PullConfigMapResult `json:"items"`
+    Failures []Failure             `json:"failures"`
+}
+
+// listAllNamespaces lists objects with one cluster-scoped list. When that is
+// forbidden, e.g. for users whose RBAC only covers some namespaces, it falls
+// back to one list per namespace; with opts.ContinueOnError namespaces that
+// fail are recorded instead of aborting.
+func listAllNamespaces[T any](ctx context.Context, o *Operations, kind string, opts ListOptions, list func(context.Context, string, ListOptions) ([]T, error)) ([]T, []Failure, error) {
+    items, err := list(ctx, metav1.NamespaceAll, opts)
+    if err == nil {
+        return items, nil, nil
+    }
+    if !apierrors.IsForbidden(err) {
+        return nil, nil, fmt.Errorf("failed to list %ss in all namespaces: %w", kind, err)
+    }
+
+    namespaces, nsErr := o.clientset.CoreV1().Namespaces().List(ctx, metav1.ListOptions{})
+    if nsErr != nil {
+        return nil, nil, fmt.Errorf("failed to list %ss in all namespaces: %w (listing namespaces: %v)", kind, err, nsErr)
+    }
+
+    items = nil
+    var failures []Failure
+    for _, ns := range namespaces.Items {
+        nsItems, err := list(ctx, ns.Name, opts)
+        if err != nil {
+            err = fmt.Errorf("failed to list %ss in namespace '%s': %w", kind, ns.Name, err)
+            if !opts.ContinueOnError {
+                return nil, nil, err
+            }
+            failures = append(failures, Failure{Kind: kind, Namespace: ns.Name, Error: err})
+            continue
+        }
+        items = append(items, nsItems...)
+    }
+    return items, failures, nil
+}
+
+// pullFailures records every pulled object with files that could not be saved
+func pullFailures(results []PullConfigMapResult) []Failure {
+    var failures []Failure
+    for _, result := range results {
+        failed := 0
+        var first error
+        for _, saved := range result.SavedFiles {
+            if saved.Success {
+                continue
+            }
+            failed++
+            if first == nil {
+                first = saved.Error
+            }
+        }
+        if failed == 0 {
+            continue
+        }
+
+        failures = append(failures, Failure{
+            Kind:      result.Kind,
+            Namespace: result.Namespace,
+            Name:      result.ConfigMapName,
+            Error:     fmt.Errorf("%d of %d file(s) failed: %w", failed, result.TotalFiles, first),
+        })
+    }
+    return failures
+}
+
+// newPullAllResult combines pulled objects with the failures of a bulk pull.
+// Without ContinueOnError the first failure is returned as the error.
+func newPullAllResult(results []PullConfigMapResult, listFailures []Failure, opts PullOptions) (*PullAllResult, error) {
+    all := &PullAllResult{
+        Results:  results,
+        Failures: append(listFailures, pullFailures(results)...),
+    }
+    if all.Results == nil {
+        all.Results = []PullConfigMapResult{}
+    }
+    if all.Failures == nil {
+        all.Failures = []Failure{}
+    }
+
+    if !opts.ContinueOnError && len(all.Failures) > 0 {
+        failure := all.Failures[0]
+        return all, fmt.Errorf("failed to pull %s '%s' in namespace '%s': %w", failure.Kind, failure.Name, failure.Namespace, failure.Error)
+    }
+    return all, nil
+}
@@ -0,0 +1,55 @@
+package configmap
+
+import "encoding/json"
+
+// MarshalJSON renders Error as a string so results can be printed as JSON or YAML
+func (r SaveResult) MarshalJSON() ([]byte, error) {
+    type plain SaveResult
+    return json.Marshal(struct {
+        plain
+        Error string `json:"error,omitempty"`
+    }{plain(r), errorString(r.Error)})
+}
+
+// MarshalJSON renders Error as a string so results can be printed as JSON or YAML
+func (r PushResult) MarshalJSON() ([]byte, error) {
+    type plain PushResult
+    return json.Marshal(struct {
+        plain
+        Error string `json:"error,omitempty"`
+    }{plain(r), errorString(r.Error)})
+}
+
+func errorString(err error) string {
+    if err == nil {
+        return ""
+    }
+    return err.Error()
+}
+
+// MarshalJSON renders Error as a string so results can be printed as JSON or YAML
+func (r RestoreResult) MarshalJSON() ([]byte, error) {
+    type plain RestoreResult
+    return json.Marshal(struct {
+        plain
+        Error string `json:"error,omitempty"`
+    }{plain(r), errorString(r.Error)})
+}
+
+// MarshalJSON renders Error as a string so failures can be printed as JSON or YAML
+func (f Failure) MarshalJSON() ([]byte, error) {
+    type plain Failure
+    return json.Marshal(struct {
+        plain
+        Error string `json:"error,omitempty"`
+    }{plain(f), errorString(f.Error)})
+}
+
+// MarshalJSON renders Error as a string so watch events can be printed as JSON or YAML
+func (e WatchEvent) MarshalJSON() ([]byte, error) {
+    type plain WatchEvent
+    return json.Marshal(struct {
+        plain
+        Error string `json:"error,omitempty"`
+    }{plain(e), errorString(e.Error)})
+}
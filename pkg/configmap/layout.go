@@ -0,0 +1,145 @@
+package configmap
+
+import (
+    "bytes"
+    "fmt"
+    "path"
+    "path/filepath"
+    "strings"
+    "text/template"
+)
+
+// Layout presets accepted by ParseLayout
+const (
+    LayoutNamespaceConfigMapKey = "ns/cm/key"
+    LayoutNamespaceKey          = "ns/key"
+    LayoutFlat                  = "flat"
+)
+
+var layoutPresets = map[string]string{
+    LayoutNamespaceConfigMapKey: "{{.Namespace}}/{{.Name}}/{{.Key}}",
+    LayoutNamespaceKey:          "{{.Namespace}}/{{.Key}}",
+    LayoutFlat:                  "{{.Key}}",
+}
+
+// Collision policies applied when two keys map to the same path
+const (
+    CollisionFail   = "fail"
+    CollisionRename = "rename"
+)
+
+// LayoutFields are the values available to a layout template
+type LayoutFields struct {
+    Namespace string
+    Name      string
+    Kind      string
+    Key       string
+}
+
+// Layout maps the keys of pulled objects to paths below the output directory
+type Layout struct {
+    source string
+    tmpl   *template.Template
+}
+
+// ParseLayout parses a preset (ns/cm/key, ns/key or flat) or a Go template
+// such as {{.Namespace}}/{{.Name}}/{{.Key}}. Templates use forward slashes
+// as separators on every platform.
+func ParseLayout(layout string) (*Layout, error) {
+    source := layout
+    if preset, ok := layoutPresets[layout]; ok {
+        source = preset
+    } else if !strings.Contains(layout, "{{") {
+        return nil, fmt.Errorf("invalid layout %q (must be one of: %s, %s, %s, or a template such as {{.Namespace}}/{{.Name}}/{{.Key}})",
+            layout, LayoutNamespaceConfigMapKey, LayoutNamespaceKey, LayoutFlat)
+    }
+
+    tmpl, err := template.New("layout").Option("missingkey=error").Parse(source)
+    if err != nil {
+        return nil, fmt.Errorf("invalid layout template %q: %w", layout, err)
+    }
+    return &Layout{source: layout, tmpl: tmpl}, nil
+}
+
+// mustParseLayout parses one of the built-in presets
+func mustParseLayout(layout string) *Layout {
+    parsed, err := ParseLayout(layout)
+    if err != nil {
+        panic(err)
+    }
+    return parsed
+}
+
+// String returns the layout as it was given to ParseLayout
+func (l *Layout) String() string {
+    return l.source
+}
+
+// Path returns the path for fields, relative to the output directory
+func (l *Layout) Path(fields LayoutFields) (string, error) {
+    var buf bytes.Buffer
+    if err := l.tmpl.Execute(&buf, fields); err != nil {
+        return "", fmt.Errorf("failed to apply layout %q: %w", l.source, err)
+    }
+
+    // Check before cleaning, which would silently fold '..' segments away
+    raw := buf.String()
+    if err := checkRelative(raw); err != nil {
+        return "", fmt.Errorf("key '%s': %w", fields.Key, err)
+    }
+    if base := path.Base(raw); base == "." || strings.HasSuffix(raw, "/") {
+        return "", fmt.Errorf("layout %q does not produce a file name for key '%s'", l.source, fields.Key)
+    }
+    return filepath.FromSlash(path.Clean(raw)), nil
+}
+
+// backupMetadataOwner owns the path of the BackupMetadataFile in a PathTracker
+const backupMetadataOwner = "backup metadata"
+
+// keyOwner names the owner of a key's path in a PathTracker
+func keyOwner(kind, namespace, name, key string) string {
+    return fmt.Sprintf("key '%s' of %s '%s/%s'", key, kind, namespace, name)
+}
+
+// PathTracker records which object key owns each output path so that keys
+// mapping to the same file are detected instead of overwriting each other.
+// A tracker can be shared by several pulls writing into the same tree.
+type PathTracker struct {
+    owners map[string]string
+}
+
+// NewPathTracker creates an empty tracker
+func NewPathTracker() *PathTracker {
+    return &PathTracker{owners: make(map[string]string)}
+}
+
+// claim reserves path for owner. With CollisionRename a taken path is
+// replaced by one prefixed with the object name (name_key), followed by a
+// numeric suffix if that is taken as well. Claiming again for the same owner
+// returns the path it already holds.
+func (t *PathTracker) claim(path, owner, name, policy string) (string, error) {
+    previous, taken := t.owners[path]
+    if !taken || previous == owner {
+        t.owners[path] = owner
+        return path, nil
+    }
+    if policy != CollisionRename {
+        return "", fmt.Errorf("path collides with %s", previous)
+    }
+
+    dir, base := filepath.Split(path)
+    candidate := filepath.Join(dir, name+"_"+base)
+    for i := 2; ; i++ {
+        if current, taken := t.owners[candidate]; !taken || current == owner {
+            t.owners[candidate] = owner
+            return candidate, nil
+        }
+        ext := filepath.Ext(base)
+        candidate = filepath.Join(dir, fmt.Sprintf("%s_%s-%d%s", name, strings.TrimSuffix(base, ext), i, ext))
+    }
+}
+
+// release frees path, e.g. once the key that owned it is gone
+func (t *PathTracker) release(path string) {
+    delete(t.owners, path)
+}
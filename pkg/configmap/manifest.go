@@ -0,0 +1,116 @@
+package configmap
+
+import (
+    "bytes"
+    "fmt"
+    "strings"
+
+    corev1 "k8s.io/api/core/v1"
+    "sigs.k8s.io/yaml"
+)
+
+// ManifestStreamFile is the file written when all manifests go into a single stream
+const ManifestStreamFile = "configmaps.yaml"
+
+// strippedAnnotations are annotations that describe live state rather than
+// desired state and would be misleading in a restored object
+var strippedAnnotations = map[string]bool{
+    "kubectl.kubernetes.io/last-applied-configuration": true,
+    "control-plane.alpha.kubernetes.io/leader":          true,
+    "kubernetes.io/change-cause":                        true,
+    "kubernetes.io/config.hash":                         true,
+    "kubernetes.io/config.mirror":                       true,
+    "kubernetes.io/config.seen":                         true,
+    "kubernetes.io/config.source":                       true,
+    "endpoints.kubernetes.io/last-change-trigger-time":  true,
+}
+
+// strippedAnnotationPrefixes cover annotations that controllers maintain,
+// such as deployment.kubernetes.io/revision
+var strippedAnnotationPrefixes = []string{
+    "deployment.kubernetes.io/",
+}
+
+// isStrippedAnnotation reports whether key only describes live state
+func isStrippedAnnotation(key string) bool {
+    if strippedAnnotations[key] {
+        return true
+    }
+    for _, prefix := range strippedAnnotationPrefixes {
+        if strings.HasPrefix(key, prefix) {
+            return true
+        }
+    }
+    return false
+}
+
+// manifest is the re-appliable form of a ConfigMap. Only fields a user could
+// have set are kept, so resourceVersion, uid, managedFields,
+// creationTimestamp and ownerReferences never appear.
+type manifest struct {
+    APIVersion string            `json:"apiVersion"`
+    Kind       string            `json:"kind"`
+    Metadata   manifestMeta      `json:"metadata"`
+    Immutable  *bool             `json:"immutable,omitempty"`
+    Data       map[string]string `json:"data,omitempty"`
+    BinaryData map[string][]byte `json:"binaryData,omitempty"`
+}
+
+type manifestMeta struct {
+    Name        string            `json:"name"`
+    Namespace   string            `json:"namespace"`
+    Labels      map[string]string `json:"labels,omitempty"`
+    Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+// cleanManifest renders a ConfigMap as YAML that can be restored with kubectl apply
+func cleanManifest(cm *corev1.ConfigMap) ([]byte, error) {
+    data, err := yaml.Marshal(manifest{
+        APIVersion: "v1",
+        Kind:       KindConfigMap,
+        Metadata: manifestMeta{
+            Name:        cm.Name,
+            Namespace:   cm.Namespace,
+            Labels:      cm.Labels,
+            Annotations: restorableAnnotations(cm.Annotations),
+        },
+        Immutable:  cm.Immutable,
+        Data:       cm.Data,
+        BinaryData: cm.BinaryData,
+    })
+    if err != nil {
+        return nil, fmt.Errorf("failed to encode ConfigMap '%s' as YAML: %w", cm.Name, err)
+    }
+    return data, nil
+}
+
+// manifestStream collects manifests for a single multi-document file
+type manifestStream struct {
+    documents bytes.Buffer
+
+    // err is the outcome of flush
+    err error
+}
+
+func (s *manifestStream) add(document []byte) {
+    if s.documents.Len() > 0 {
+        s.documents.WriteString("---\n")
+    }
+    s.documents.Write(document)
+}
+
+// flush writes the stream to sink and records the outcome on every result
+// it holds
+func (s *manifestStream) flush(sink Sink, results []*PullConfigMapResult) {
+    path, err := sink.Resolve(ManifestStreamFile)
+    if err == nil {
+        err = sink.WriteFile(ManifestStreamFile, s.documents.Bytes(), 0644)
+    }
+    s.err = err
+    saveResult := SaveResult{Path: path, Success: err == nil, Error: err, rel: ManifestStreamFile}
+
+    for _, result := range results {
+        result.SavedFiles = append(result.SavedFiles, saveResult)
+        result.TotalFiles++
+    }
+}
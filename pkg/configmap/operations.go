@@ -5,37 +5,113 @@ import (
     "fmt"
     "os"
     "path/filepath"
+    "sort"
+    "strings"
+    "time"
 
     corev1 "k8s.io/api/core/v1"
     metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
     "k8s.io/client-go/kubernetes"
 )
 
+// Resource kinds handled by Operations
+const (
+    KindConfigMap = "ConfigMap"
+    KindSecret    = "Secret"
+)
+
+// Key actions reported when comparing local files with a ConfigMap
+const (
+    KeyAdded     = "added"
+    KeyUpdated   = "updated"
+    KeyUnchanged = "unchanged"
+    KeyRemoved   = "removed"
+    KeyChanged   = "changed"
+)
+
 // Operations handles ConfigMap operations
 type Operations struct {
-    clientset *kubernetes.Clientset
+    clientset kubernetes.Interface
+    pageSize  int64
+    now       func() time.Time
+}
+
+// Option configures Operations
+type Option func(*Operations)
+
+// WithPageSize sets the number of objects requested per list call,
+// DefaultPageSize by default
+func WithPageSize(size int64) Option {
+    return func(o *Operations) {
+        o.pageSize = size
+    }
 }
 
-// NewOperations creates a new ConfigMap operations handler
-func NewOperations(clientset *kubernetes.Clientset) *Operations {
-    return &Operations{
+// WithClock sets the clock used for age thresholds, time.Now by default
+func WithClock(now func() time.Time) Option {
+    return func(o *Operations) {
+        o.now = now
+    }
+}
+
+// NewOperations creates a new ConfigMap operations handler. clientset may be
+// a real clientset or k8s.io/client-go/kubernetes/fake for tests.
+func NewOperations(clientset kubernetes.Interface, opts ...Option) *Operations {
+    o := &Operations{
         clientset: clientset,
+        pageSize:  DefaultPageSize,
+        now:       time.Now,
+    }
+    for _, opt := range opts {
+        opt(o)
     }
+    return o
 }
 
 // ConfigMapInfo represents ConfigMap information
 type ConfigMapInfo struct {
-    Name        string
-    Namespace   string
-    DataKeys    []string
-    BinaryKeys  []string
-    DataCount   int
-    BinaryCount int
+    Name        string   `json:"name"`
+    Namespace   string   `json:"namespace"`
+    DataKeys    []string `json:"dataKeys"`
+    BinaryKeys  []string `json:"binaryKeys"`
+    DataCount   int      `json:"dataCount"`
+    BinaryCount int      `json:"binaryCount"`
+    // Consumers lists the workloads using the ConfigMap as kind/name. It is
+    // only filled in by AddConsumers.
+    Consumers []string `json:"consumers,omitempty"`
+}
+
+// AddConsumers records the consumers found by FindConsumers on configMaps.
+// Consumers stays nil unless the consumers were looked up.
+func AddConsumers(configMaps []ConfigMapInfo, consumers map[string][]Consumer) {
+    for i := range configMaps {
+        configMaps[i].Consumers = []string{}
+        for _, consumer := range consumers[configMaps[i].Namespace+"/"+configMaps[i].Name] {
+            configMaps[i].Consumers = append(configMaps[i].Consumers, consumer.String())
+        }
+    }
+}
+
+// ListOptions narrows down which objects are listed
+type ListOptions struct {
+    LabelSelector string
+    FieldSelector string
+
+    // ContinueOnError records namespaces and objects that fail during bulk
+    // operations as Failures instead of aborting on the first one
+    ContinueOnError bool
+}
+
+// toMeta converts the options to the API list options
+func (l ListOptions) toMeta() metav1.ListOptions {
+    return metav1.ListOptions{
+        LabelSelector: l.LabelSelector,
+        FieldSelector: l.FieldSelector,
+    }
 }
 
 // GetConfigMap retrieves a specific ConfigMap
-func (o *Operations) GetConfigMap(namespace, name string) (*corev1.ConfigMap, error) {
-    ctx := context.Background()
+func (o *Operations) GetConfigMap(ctx context.Context, namespace, name string) (*corev1.ConfigMap, error) {
     configMap, err := o.clientset.CoreV1().ConfigMaps(namespace).Get(ctx, name, metav1.GetOptions{})
     if err != nil {
         return nil, fmt.Errorf("failed to get ConfigMap '%s' in namespace '%s': %w", name, namespace, err)
@@ -43,155 +119,133 @@ func (o *Operations) GetConfigMap(namespace, name string) (*corev1.ConfigMap, er
     return configMap, nil
 }
 
-// ListConfigMaps lists all ConfigMaps in a namespace
-func (o *Operations) ListConfigMaps(namespace string) ([]ConfigMapInfo, error) {
-    ctx := context.Background()
-    configMaps, err := o.clientset.CoreV1().ConfigMaps(namespace).List(ctx, metav1.ListOptions{})
+// DefaultPageSize is the number of objects requested per list call by default
+const DefaultPageSize = 500
+
+// ListConfigMaps lists the ConfigMaps in a namespace matching opts
+func (o *Operations) ListConfigMaps(ctx context.Context, namespace string, opts ListOptions) ([]ConfigMapInfo, error) {
+    configMaps, err := o.listConfigMaps(ctx, namespace, opts)
     if err != nil {
         return nil, fmt.Errorf("failed to list ConfigMaps in namespace '%s': %w", namespace, err)
     }
 
     var infos []ConfigMapInfo
-    for _, cm := range configMaps.Items {
-        var dataKeys, binaryKeys []string
-        for key := range cm.Data {
-            dataKeys = append(dataKeys, key)
-        }
-        for key := range cm.BinaryData {
-            binaryKeys = append(binaryKeys, key)
-        }
-
-        infos = append(infos, ConfigMapInfo{
-            Name:        cm.Name,
-            Namespace:   cm.Namespace,
-            DataKeys:    dataKeys,
-            BinaryKeys:  binaryKeys,
-            DataCount:   len(cm.Data),
-            BinaryCount: len(cm.BinaryData),
-        })
+    for i := range configMaps {
+        infos = append(infos, configMapInfo(&configMaps[i]))
     }
-
     return infos, nil
 }
 
-// ListAllConfigMaps lists ConfigMaps from all namespaces
-func (o *Operations) ListAllConfigMaps() (map[string][]ConfigMapInfo, error) {
-    ctx := context.Background()
-    namespaces, err := o.clientset.CoreV1().Namespaces().List(ctx, metav1.ListOptions{})
+// ListAllConfigMaps lists ConfigMaps matching opts from all namespaces. With
+// opts.ContinueOnError, namespaces that cannot be listed are returned as
+// failures.
+func (o *Operations) ListAllConfigMaps(ctx context.Context, opts ListOptions) (map[string][]ConfigMapInfo, []Failure, error) {
+    configMaps, failures, err := listAllNamespaces(ctx, o, KindConfigMap, opts, o.listConfigMaps)
     if err != nil {
-        return nil, fmt.Errorf("failed to list namespaces: %w", err)
+        return nil, nil, err
     }
 
     result := make(map[string][]ConfigMapInfo)
-    for _, ns := range namespaces.Items {
-        configMaps, err := o.ListConfigMaps(ns.Name)
-        if err != nil {
-            return nil, err
-        }
-        if len(configMaps) > 0 {
-            result[ns.Name] = configMaps
-        }
+    for i := range configMaps {
+        info := configMapInfo(&configMaps[i])
+        result[info.Namespace] = append(result[info.Namespace], info)
     }
-
-    return result, nil
-}
-
-// SaveResult represents the result of saving a file
-type SaveResult struct {
-    Path    string
-    Success bool
-    Error   error
-    Binary  bool
-}
-
-// PullConfigMapResult represents the result of pulling a ConfigMap
-type PullConfigMapResult struct {
-    ConfigMapName string
-    Namespace     string
-    SavedFiles    []SaveResult
-    TotalFiles    int
+    return result, failures, nil
 }
 
-// PullConfigMap saves a ConfigMap's data to files
-func (o *Operations) PullConfigMap(namespace, name, outputDir string) (*PullConfigMapResult, error) {
-    configMap, err := o.GetConfigMap(namespace, name)
+// listConfigMaps fetches the ConfigMaps matching opts page by page. With
+// metav1.NamespaceAll a single cluster-scoped list covers every namespace.
+func (o *Operations) listConfigMaps(ctx context.Context, namespace string, opts ListOptions) ([]corev1.ConfigMap, error) {
+    var configMaps []corev1.ConfigMap
+    err := listPages(ctx, o, opts.toMeta(), o.clientset.CoreV1().ConfigMaps(namespace).List, func(page *corev1.ConfigMapList) {
+        configMaps = append(configMaps, page.Items...)
+    })
     if err != nil {
         return nil, err
     }
+    return configMaps, nil
+}
 
-    if err := os.MkdirAll(outputDir, 0755); err != nil {
-        return nil, fmt.Errorf("failed to create output directory: %w", err)
-    }
-
-    result := &PullConfigMapResult{
-        ConfigMapName: name,
-        Namespace:     namespace,
-        SavedFiles:    []SaveResult{},
-    }
-
-    // Handle text data
-    for key, value := range configMap.Data {
-        outputPath := filepath.Join(outputDir, key)
-        saveResult := SaveResult{
-            Path:   outputPath,
-            Binary: false,
+// listPages calls list with o.pageSize objects per page, following the
+// continue token until the last page, and hands every page to add
+func listPages[L metav1.ListInterface](ctx context.Context, o *Operations, listOptions metav1.ListOptions, list func(context.Context, metav1.ListOptions) (L, error), add func(L)) error {
+    listOptions.Limit = o.pageSize
+    for {
+        page, err := list(ctx, listOptions)
+        if err != nil {
+            return err
         }
-
-        if err := os.WriteFile(outputPath, []byte(value), 0644); err != nil {
-            saveResult.Success = false
-            saveResult.Error = err
-        } else {
-            saveResult.Success = true
+        add(page)
+        if page.GetContinue() == "" {
+            return nil
         }
-
-        result.SavedFiles = append(result.SavedFiles, saveResult)
-        result.TotalFiles++
+        listOptions.Continue = page.GetContinue()
     }
+}
 
-    // Handle binary data
-    for key, value := range configMap.BinaryData {
-        outputPath := filepath.Join(outputDir, key)
-        saveResult := SaveResult{
-            Path:   outputPath,
-            Binary: true,
-        }
-
-        if err := os.WriteFile(outputPath, value, 0644); err != nil {
-            saveResult.Success = false
-            saveResult.Error = err
-        } else {
-            saveResult.Success = true
-        }
-
-        result.SavedFiles = append(result.SavedFiles, saveResult)
-        result.TotalFiles++
+// configMapInfo summarizes a ConfigMap's keys
+func configMapInfo(cm *corev1.ConfigMap) ConfigMapInfo {
+    dataKeys := make([]string, 0, len(cm.Data))
+    binaryKeys := make([]string, 0, len(cm.BinaryData))
+    for key := range cm.Data {
+        dataKeys = append(dataKeys, key)
     }
+    for key := range cm.BinaryData {
+        binaryKeys = append(binaryKeys, key)
+    }
+    sort.Strings(dataKeys)
+    sort.Strings(binaryKeys)
+
+    return ConfigMapInfo{
+        Name:        cm.Name,
+        Namespace:   cm.Namespace,
+        DataKeys:    dataKeys,
+        BinaryKeys:  binaryKeys,
+        DataCount:   len(cm.Data),
+        BinaryCount: len(cm.BinaryData),
+    }
+}
 
-    return result, nil
+// SaveResult represents the result of saving a file
+type SaveResult struct {
+    Key     string `json:"key,omitempty"`
+    Path    string `json:"path"`
+    Success bool   `json:"success"`
+    Error   error  `json:"-"`
+    Binary  bool   `json:"binary"`
+
+    // rel is the path below the output the file was planned at
+    rel string
 }
 
-// PullAllConfigMaps saves all ConfigMaps from all namespaces
-func (o *Operations) PullAllConfigMaps(outputDir string) ([]PullConfigMapResult, error) {
-    allConfigMaps, err := o.ListAllConfigMaps()
+// localFiles returns the sorted names of the regular, non-hidden files in dir,
+// i.e. the files that map to ConfigMap keys
+func localFiles(dir string) ([]string, error) {
+    entries, err := os.ReadDir(dir)
     if err != nil {
-        return nil, err
+        return nil, fmt.Errorf("failed to read directory '%s': %w", dir, err)
     }
 
-    var results []PullConfigMapResult
-    for namespace, configMaps := range allConfigMaps {
-        for _, cm := range configMaps {
-            if cm.DataCount == 0 && cm.BinaryCount == 0 {
-                continue // Skip empty ConfigMaps
-            }
-
-            nsDir := filepath.Join(outputDir, namespace)
-            result, err := o.PullConfigMap(namespace, cm.Name, nsDir)
-            if err != nil {
-                return results, fmt.Errorf("failed to pull ConfigMap '%s' from namespace '%s': %w", cm.Name, namespace, err)
-            }
-            results = append(results, *result)
+    var names []string
+    for _, entry := range entries {
+        name := entry.Name()
+        if strings.HasPrefix(name, ".") {
+            continue
+        }
+        info, err := os.Stat(filepath.Join(dir, name))
+        if err != nil || !info.Mode().IsRegular() {
+            continue
         }
+        names = append(names, name)
     }
+    return names, nil
+}
 
-    return results, nil
-}
\ No newline at end of file
+// PullConfigMapResult represents the result of pulling a ConfigMap
+type PullConfigMapResult struct {
+    Kind          string       `json:"kind"`
+    ConfigMapName string       `json:"name"`
+    Namespace     string       `json:"namespace"`
+    SavedFiles    []SaveResult `json:"savedFiles"`
+    TotalFiles    int          `json:"totalFiles"`
+}
@@ -0,0 +1,517 @@
+package configmap
+
+import (
+    "bytes"
+    "context"
+    "errors"
+    "os"
+    "path/filepath"
+    "reflect"
+    "sync"
+    "testing"
+
+    corev1 "k8s.io/api/core/v1"
+    apierrors "k8s.io/apimachinery/pkg/api/errors"
+    metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+    "k8s.io/apimachinery/pkg/runtime"
+    "k8s.io/apimachinery/pkg/runtime/schema"
+    "k8s.io/client-go/kubernetes/fake"
+    k8stesting "k8s.io/client-go/testing"
+)
+
+func TestListConfigMaps(t *testing.T) {
+    ops := NewOperations(fake.NewClientset(
+        newConfigMap("default", "app", map[string]string{"b.yaml": "b", "a.yaml": "a"}, map[string]string{"app": "payments"}),
+        newBinaryConfigMap("default", "certs", map[string][]byte{"ca.der": {0xff}}),
+        newConfigMap("other", "app", map[string]string{"c.yaml": "c"}, nil),
+    ))
+
+    tests := []struct {
+        name string
+        opts ListOptions
+        want []ConfigMapInfo
+    }{
+        {
+            name: "whole namespace",
+            want: []ConfigMapInfo{
+                {Name: "app", Namespace: "default", DataKeys: []string{"a.yaml", "b.yaml"}, BinaryKeys: []string{}, DataCount: 2},
+                {Name: "certs", Namespace: "default", DataKeys: []string{}, BinaryKeys: []string{"ca.der"}, BinaryCount: 1},
+            },
+        },
+        {
+            name: "label selector",
+            opts: ListOptions{LabelSelector: "app=payments"},
+            want: []ConfigMapInfo{
+                {Name: "app", Namespace: "default", DataKeys: []string{"a.yaml", "b.yaml"}, BinaryKeys: []string{}, DataCount: 2},
+            },
+        },
+        {
+            name: "no match",
+            opts: ListOptions{LabelSelector: "app=missing"},
+        },
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            got, err := ops.ListConfigMaps(context.Background(), "default", tt.opts)
+            if err != nil {
+                t.Fatalf("ListConfigMaps failed: %v", err)
+            }
+            if !reflect.DeepEqual(got, tt.want) {
+                t.Errorf("ListConfigMaps = %+v, want %+v", got, tt.want)
+            }
+        })
+    }
+}
+
+func TestListConfigMapsPageSize(t *testing.T) {
+    clientset := fake.NewClientset(newConfigMap("default", "app", map[string]string{"a": "a"}, nil))
+    var limits []int64
+    clientset.PrependReactor("list", "configmaps", func(action k8stesting.Action) (bool, runtime.Object, error) {
+        limits = append(limits, action.(k8stesting.ListActionImpl).GetListOptions().Limit)
+        return false, nil, nil
+    })
+
+    if _, err := NewOperations(clientset, WithPageSize(50)).ListConfigMaps(context.Background(), "default", ListOptions{}); err != nil {
+        t.Fatal(err)
+    }
+    if _, err := NewOperations(clientset).ListConfigMaps(context.Background(), "default", ListOptions{}); err != nil {
+        t.Fatal(err)
+    }
+    if want := []int64{50, DefaultPageSize}; !reflect.DeepEqual(limits, want) {
+        t.Errorf("list limits = %v, want %v", limits, want)
+    }
+}
+
+func TestListAllConfigMaps(t *testing.T) {
+    ops := NewOperations(fake.NewClientset(
+        newConfigMap("default", "app", map[string]string{"a": "a"}, nil),
+        newConfigMap("team", "app", map[string]string{"b": "b"}, nil),
+        newConfigMap("team", "web", map[string]string{"c": "c"}, nil),
+    ))
+
+    got, failures, err := ops.ListAllConfigMaps(context.Background(), ListOptions{})
+    if err != nil {
+        t.Fatalf("ListAllConfigMaps failed: %v", err)
+    }
+    if len(failures) != 0 {
+        t.Errorf("failures = %v, want none", failures)
+    }
+    if len(got) != 2 || len(got["default"]) != 1 || len(got["team"]) != 2 {
+        t.Fatalf("ListAllConfigMaps = %+v, want 1 ConfigMap in default and 2 in team", got)
+    }
+    if got["team"][0].Name != "app" || got["team"][1].Name != "web" {
+        t.Errorf("team ConfigMaps = %+v, want app and web", got["team"])
+    }
+}
+
+func TestListAllConfigMapsForbidden(t *testing.T) {
+    newOps := func() *Operations {
+        clientset := fake.NewClientset(
+            newNamespace("default"),
+            newNamespace("secret-team"),
+            newConfigMap("default", "app", map[string]string{"a": "a"}, nil),
+            newConfigMap("secret-team", "app", map[string]string{"b": "b"}, nil),
+        )
+        clientset.PrependReactor("list", "configmaps", func(action k8stesting.Action) (bool, runtime.Object, error) {
+            switch action.GetNamespace() {
+            case metav1.NamespaceAll, "secret-team":
+                return true, nil, apierrors.NewForbidden(schema.GroupResource{Resource: "configmaps"}, "", nil)
+            }
+            return false, nil, nil
+        })
+        return NewOperations(clientset)
+    }
+
+    got, failures, err := newOps().ListAllConfigMaps(context.Background(), ListOptions{ContinueOnError: true})
+    if err != nil {
+        t.Fatalf("ListAllConfigMaps failed: %v", err)
+    }
+    if len(got["default"]) != 1 || len(got["secret-team"]) != 0 {
+        t.Errorf("ListAllConfigMaps = %+v, want only the ConfigMap in default", got)
+    }
+    if len(failures) != 1 || failures[0].Namespace != "secret-team" || !apierrors.IsForbidden(failures[0].Error) {
+        t.Errorf("failures = %+v, want secret-team forbidden", failures)
+    }
+
+    if _, _, err := newOps().ListAllConfigMaps(context.Background(), ListOptions{}); !apierrors.IsForbidden(err) {
+        t.Errorf("ListAllConfigMaps without ContinueOnError: error = %v, want forbidden", err)
+    }
+}
+
+func TestDiffAllConfigMapsForbidden(t *testing.T) {
+    clientset := fake.NewClientset(
+        newNamespace("default"),
+        newNamespace("secret-team"),
+        newConfigMap("default", "app", map[string]string{"a": "a"}, nil),
+    )
+    clientset.PrependReactor("list", "configmaps", func(action k8stesting.Action) (bool, runtime.Object, error) {
+        switch action.GetNamespace() {
+        case metav1.NamespaceAll, "secret-team":
+            return true, nil, apierrors.NewForbidden(schema.GroupResource{Resource: "configmaps"}, "", nil)
+        }
+        return false, nil, nil
+    })
+
+    results, failures, err := NewOperations(clientset).DiffAllConfigMaps(context.Background(), t.TempDir(), nil)
+    if err != nil {
+        t.Fatalf("DiffAllConfigMaps failed: %v", err)
+    }
+    if len(results) != 1 || results[0].ConfigMapName != "app" {
+        t.Errorf("results = %+v, want default/app", results)
+    }
+    if len(failures) != 1 || failures[0].Namespace != "secret-team" {
+        t.Errorf("failures = %+v, want secret-team", failures)
+    }
+}
+
+func TestPullConfigMap(t *testing.T) {
+    root := t.TempDir()
+    ops := NewOperations(fake.NewClientset(
+        newConfigMap("default", "app", map[string]string{"app.yaml": "port: 80", "nested/log.conf": "level=info"}, nil),
+        newBinaryConfigMap("default", "certs", map[string][]byte{"ca.der": {0x30, 0x82, 0xff}}),
+    ))
+
+    result, err := ops.PullConfigMap(context.Background(), "default", "app", root, PullOptions{})
+    if err != nil {
+        t.Fatalf("PullConfigMap failed: %v", err)
+    }
+    if result.Kind != KindConfigMap || result.ConfigMapName != "app" || result.Namespace != "default" || result.TotalFiles != 2 {
+        t.Errorf("result = %+v, want 2 files of ConfigMap default/app", result)
+    }
+    for _, saved := range result.SavedFiles {
+        if !saved.Success {
+            t.Errorf("key %q was not saved: %v", saved.Key, saved.Error)
+        }
+    }
+    assertFile(t, filepath.Join(root, "app.yaml"), "port: 80")
+    assertFile(t, filepath.Join(root, "nested", "log.conf"), "level=info")
+
+    binary, err := ops.PullConfigMap(context.Background(), "default", "certs", root, PullOptions{})
+    if err != nil {
+        t.Fatalf("PullConfigMap of binary data failed: %v", err)
+    }
+    if len(binary.SavedFiles) != 1 || !binary.SavedFiles[0].Binary {
+        t.Errorf("SavedFiles = %+v, want one binary file", binary.SavedFiles)
+    }
+    assertFile(t, filepath.Join(root, "ca.der"), "\x30\x82\xff")
+}
+
+func TestPullConfigMapNotFound(t *testing.T) {
+    ops := NewOperations(fake.NewClientset())
+
+    if _, err := ops.PullConfigMap(context.Background(), "default", "missing", t.TempDir(), PullOptions{}); !apierrors.IsNotFound(err) {
+        t.Errorf("PullConfigMap of a missing ConfigMap: error = %v, want not found", err)
+    }
+}
+
+func TestPullConfigMapManifest(t *testing.T) {
+    root := t.TempDir()
+    configMap := newConfigMap("default", "app", map[string]string{"app.yaml": "port: 80"}, nil)
+    configMap.ResourceVersion = "42"
+    configMap.Annotations = map[string]string{
+        "kubectl.kubernetes.io/last-applied-configuration": "{}",
+        "kubernetes.io/change-cause":                        "kubectl apply",
+        "deployment.kubernetes.io/revision":                 "3",
+        "team":                                              "payments",
+    }
+    ops := NewOperations(fake.NewClientset(configMap))
+
+    if _, err := ops.PullConfigMap(context.Background(), "default", "app", root, PullOptions{As: PullAsManifest}); err != nil {
+        t.Fatalf("PullConfigMap failed: %v", err)
+    }
+    manifest, err := os.ReadFile(filepath.Join(root, "app.yaml"))
+    if err != nil {
+        t.Fatal(err)
+    }
+    if want := "kind: ConfigMap"; !contains(manifest, want) {
+        t.Errorf("manifest does not contain %q:\n%s", want, manifest)
+    }
+    for _, stripped := range []string{"resourceVersion", "last-applied-configuration", "change-cause", "deployment.kubernetes.io"} {
+        if contains(manifest, stripped) {
+            t.Errorf("manifest still contains %s:\n%s", stripped, manifest)
+        }
+    }
+    if want := "team: payments"; !contains(manifest, want) {
+        t.Errorf("manifest does not contain %q:\n%s", want, manifest)
+    }
+}
+
+func TestPullAllConfigMaps(t *testing.T) {
+    root := t.TempDir()
+    ops := NewOperations(fake.NewClientset(
+        newConfigMap("default", "app", map[string]string{"app.yaml": "a"}, nil),
+        newConfigMap("team", "web", map[string]string{"web.yaml": "w"}, map[string]string{"app": "web"}),
+        newConfigMap("team", "empty", nil, nil),
+    ))
+
+    all, err := ops.PullAllConfigMaps(context.Background(), root, PullOptions{})
+    if err != nil {
+        t.Fatalf("PullAllConfigMaps failed: %v", err)
+    }
+    if len(all.Failures) != 0 {
+        t.Errorf("failures = %+v, want none", all.Failures)
+    }
+
+    var pulled []string
+    for _, result := range all.Results {
+        pulled = append(pulled, result.Namespace+"/"+result.ConfigMapName)
+    }
+    if want := []string{"default/app", "team/web"}; !reflect.DeepEqual(pulled, want) {
+        t.Errorf("pulled %v, want %v (empty ConfigMaps are skipped)", pulled, want)
+    }
+    assertFile(t, filepath.Join(root, "default", "app.yaml"), "a")
+    assertFile(t, filepath.Join(root, "team", "web.yaml"), "w")
+    if _, err := os.Stat(filepath.Join(root, BackupMetadataFile)); err != nil {
+        t.Errorf("backup metadata was not written: %v", err)
+    }
+
+    selected, err := ops.PullAllConfigMaps(context.Background(), t.TempDir(), PullOptions{ListOptions: ListOptions{LabelSelector: "app=web"}})
+    if err != nil {
+        t.Fatalf("PullAllConfigMaps with a selector failed: %v", err)
+    }
+    if len(selected.Results) != 1 || selected.Results[0].ConfigMapName != "web" {
+        t.Errorf("results = %+v, want only team/web", selected.Results)
+    }
+}
+
+func TestPullAllConfigMapsCollisions(t *testing.T) {
+    newOps := func() *Operations {
+        return NewOperations(fake.NewClientset(
+            newConfigMap("default", "api", map[string]string{"config.yaml": "api"}, nil),
+            newConfigMap("default", "web", map[string]string{"config.yaml": "web"}, nil),
+        ))
+    }
+
+    root := t.TempDir()
+    all, err := newOps().PullAllConfigMaps(context.Background(), root, PullOptions{ListOptions: ListOptions{ContinueOnError: true}})
+    if err != nil {
+        t.Fatalf("PullAllConfigMaps failed: %v", err)
+    }
+    if len(all.Failures) != 1 || all.Failures[0].Name != "web" {
+        t.Errorf("failures = %+v, want the colliding key of web", all.Failures)
+    }
+    assertFile(t, filepath.Join(root, "default", "config.yaml"), "api")
+
+    if _, err := newOps().PullAllConfigMaps(context.Background(), t.TempDir(), PullOptions{}); err == nil {
+        t.Error("PullAllConfigMaps with a collision and without ContinueOnError succeeded")
+    }
+
+    root = t.TempDir()
+    if _, err := newOps().PullAllConfigMaps(context.Background(), root, PullOptions{OnCollision: CollisionRename}); err != nil {
+        t.Fatalf("PullAllConfigMaps with CollisionRename failed: %v", err)
+    }
+    assertFile(t, filepath.Join(root, "default", "config.yaml"), "api")
+    assertFile(t, filepath.Join(root, "default", "web_config.yaml"), "web")
+
+    // diff finds the renamed file through the backup metadata
+    diffs, failures, err := newOps().DiffAllConfigMaps(context.Background(), root, nil)
+    if err != nil || len(failures) != 0 {
+        t.Fatalf("DiffAllConfigMaps failed: %v %+v", err, failures)
+    }
+    for _, result := range diffs {
+        if result.HasDrift() {
+            t.Errorf("%s/%s drifted: %+v, want no drift right after a pull", result.Namespace, result.ConfigMapName, result.Changes)
+        }
+    }
+}
+
+func TestPullAllConfigMapsReservesBackupMetadata(t *testing.T) {
+    root := t.TempDir()
+    ops := NewOperations(fake.NewClientset(
+        newConfigMap("default", "app", map[string]string{BackupMetadataFile: "configMaps: oops", "app.yaml": "a"}, nil),
+    ))
+
+    flat := mustParseLayout(LayoutFlat)
+    all, err := ops.PullAllConfigMaps(context.Background(), root, PullOptions{ListOptions: ListOptions{ContinueOnError: true}, Layout: flat})
+    if err != nil {
+        t.Fatalf("PullAllConfigMaps failed: %v", err)
+    }
+    if len(all.Failures) != 1 {
+        t.Errorf("failures = %+v, want the key colliding with the backup metadata", all.Failures)
+    }
+    metadata, err := ReadBackupMetadata(root)
+    if err != nil {
+        t.Fatalf("backup metadata was overwritten: %v", err)
+    }
+    if len(metadata.ConfigMaps) != 1 || !metadata.ConfigMaps[0].Incomplete {
+        t.Errorf("metadata = %+v, want app marked incomplete", metadata)
+    }
+}
+
+func TestPullAllConfigMapsCancelled(t *testing.T) {
+    root := t.TempDir()
+    ops := NewOperations(fake.NewClientset(
+        newConfigMap("default", "app", map[string]string{"app.yaml": "a"}, nil),
+    ))
+
+    ctx, cancel := context.WithCancel(context.Background())
+    cancel()
+    all, err := ops.PullAllConfigMaps(ctx, root, PullOptions{ListOptions: ListOptions{ContinueOnError: true}})
+    if !errors.Is(err, context.Canceled) {
+        t.Fatalf("PullAllConfigMaps error = %v, want context.Canceled", err)
+    }
+    if all == nil || len(all.Results) != 1 {
+        t.Fatalf("result = %+v, want the planned ConfigMap", all)
+    }
+    if saved := all.Results[0].SavedFiles[0]; saved.Success || !errors.Is(saved.Error, context.Canceled) {
+        t.Errorf("saved = %+v, want a file that was not written", saved)
+    }
+    if len(all.Failures) != 1 {
+        t.Errorf("failures = %+v, want the unwritten ConfigMap", all.Failures)
+    }
+    if _, err := os.Stat(filepath.Join(root, "default", "app.yaml")); !os.IsNotExist(err) {
+        t.Errorf("app.yaml was written after cancellation: %v", err)
+    }
+    if _, err := os.Stat(filepath.Join(root, BackupMetadataFile)); err != nil {
+        t.Errorf("backup metadata was not written: %v", err)
+    }
+}
+
+func TestPullAllConfigMapsAtomic(t *testing.T) {
+    root := t.TempDir()
+    ops := NewOperations(fake.NewClientset(
+        newConfigMap("default", "app", map[string]string{"app.yaml": "a"}, nil),
+    ))
+
+    if _, err := ops.PullAllConfigMaps(context.Background(), root, PullOptions{Atomic: true}); err != nil {
+        t.Fatalf("PullAllConfigMaps failed: %v", err)
+    }
+    target, err := os.Readlink(filepath.Join(root, BackupMetadataFile))
+    if err != nil {
+        t.Fatalf("backup metadata is not published through ..data: %v", err)
+    }
+    if want := filepath.Join(atomicDataDir, BackupMetadataFile); target != want {
+        t.Errorf("backup metadata links to %q, want %q", target, want)
+    }
+    if _, err := ReadBackupMetadata(root); err != nil {
+        t.Errorf("ReadBackupMetadata failed: %v", err)
+    }
+    assertFile(t, filepath.Join(root, "default", "app.yaml"), "a")
+}
+
+// cancellingSink cancels a pull once it has written a given number of files
+type cancellingSink struct {
+    *DirSink
+    cancel context.CancelFunc
+    after  int
+    mu     sync.Mutex
+}
+
+func (c *cancellingSink) WriteFile(rel string, data []byte, perm os.FileMode) error {
+    if err := c.DirSink.WriteFile(rel, data, perm); err != nil {
+        return err
+    }
+    c.mu.Lock()
+    defer c.mu.Unlock()
+    if c.after--; c.after == 0 {
+        c.cancel()
+    }
+    return nil
+}
+
+func TestPullAllConfigMapsInterruptedBackup(t *testing.T) {
+    root := t.TempDir()
+    ops := NewOperations(fake.NewClientset(
+        newConfigMap("default", "api", map[string]string{"api.yaml": "a"}, nil),
+        newConfigMap("default", "web", map[string]string{"web.yaml": "w", "web.json": "{}"}, nil),
+        newConfigMap("team", "worker", map[string]string{"worker.yaml": "w"}, nil),
+    ))
+
+    ctx, cancel := context.WithCancel(context.Background())
+    defer cancel()
+    sink := &cancellingSink{DirSink: NewDirSink(root), cancel: cancel, after: 1}
+    _, err := ops.PullAllConfigMaps(ctx, root, PullOptions{ListOptions: ListOptions{ContinueOnError: true}, Sink: sink})
+    if !errors.Is(err, context.Canceled) {
+        t.Fatalf("PullAllConfigMaps error = %v, want context.Canceled", err)
+    }
+
+    metadata, err := ReadBackupMetadata(root)
+    if err != nil {
+        t.Fatalf("ReadBackupMetadata failed: %v", err)
+    }
+    var recorded []string
+    for _, entry := range metadata.ConfigMaps {
+        recorded = append(recorded, entry.Namespace+"/"+entry.Name)
+        if entry.Incomplete || len(entry.Keys) == 0 {
+            t.Errorf("entry %+v is partial", entry)
+        }
+    }
+    if want := []string{"default/api"}; !reflect.DeepEqual(recorded, want) {
+        t.Errorf("recorded %v, want %v", recorded, want)
+    }
+}
+
+func newConfigMap(namespace, name string, data, labels map[string]string) *corev1.ConfigMap {
+    return &corev1.ConfigMap{
+        ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace, Labels: labels},
+        Data:       data,
+    }
+}
+
+func newBinaryConfigMap(namespace, name string, binaryData map[string][]byte) *corev1.ConfigMap {
+    return &corev1.ConfigMap{
+        ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+        BinaryData: binaryData,
+    }
+}
+
+func newNamespace(name string) *corev1.Namespace {
+    return &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: name}}
+}
+
+func assertFile(t *testing.T, path, want string) {
+    t.Helper()
+    got, err := os.ReadFile(path)
+    if err != nil {
+        t.Errorf("reading %s: %v", path, err)
+        return
+    }
+    if string(got) != want {
+        t.Errorf("%s = %q, want %q", path, got, want)
+    }
+}
+
+func contains(data []byte, substring string) bool {
+    return bytes.Contains(data, []byte(substring))
+}
+
+func TestRestoreRefusesIncompleteEntries(t *testing.T) {
+    root := t.TempDir()
+    ops := NewOperations(fake.NewClientset(
+        newConfigMap("default", "api", map[string]string{"config.yaml": "api"}, nil),
+        newConfigMap("default", "web", map[string]string{"config.yaml": "web", "web.yaml": "w"}, nil),
+    ))
+    if _, err := ops.PullAllConfigMaps(context.Background(), root, PullOptions{ListOptions: ListOptions{ContinueOnError: true}}); err != nil {
+        t.Fatalf("PullAllConfigMaps failed: %v", err)
+    }
+
+    metadata, err := ReadBackupMetadata(root)
+    if err != nil {
+        t.Fatalf("ReadBackupMetadata failed: %v", err)
+    }
+    incomplete := map[string]bool{}
+    for _, entry := range metadata.ConfigMaps {
+        incomplete[entry.Name] = entry.Incomplete
+    }
+    if want := map[string]bool{"api": false, "web": true}; !reflect.DeepEqual(incomplete, want) {
+        t.Fatalf("incomplete = %v, want %v", incomplete, want)
+    }
+
+    results, err := ops.RestoreConfigMaps(context.Background(), root, RestoreOptions{OnConflict: ConflictOverwrite})
+    if err != nil {
+        t.Fatalf("RestoreConfigMaps failed: %v", err)
+    }
+    for _, result := range results {
+        if (result.Error != nil) != (result.ConfigMapName == "web") {
+            t.Errorf("restoring %s: error = %v", result.ConfigMapName, result.Error)
+        }
+    }
+    web, err := ops.GetConfigMap(context.Background(), "default", "web")
+    if err != nil {
+        t.Fatal(err)
+    }
+    if len(web.Data) != 2 {
+        t.Errorf("web data = %v, want both keys kept", web.Data)
+    }
+}
@@ -0,0 +1,329 @@
+package configmap
+
+import (
+    "context"
+    "fmt"
+    "os"
+    "path"
+    "regexp"
+    "sort"
+    "strings"
+
+    corev1 "k8s.io/api/core/v1"
+    apierrors "k8s.io/apimachinery/pkg/api/errors"
+    metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// EnvFile is the file the ConfigMap-sourced environment of a container is
+// written to
+const EnvFile = ".env"
+
+// defaultVolumeMode is the mode the kubelet gives ConfigMap volume files
+// when the volume sets no defaultMode
+const defaultVolumeMode = 0644
+
+// PodPullResult is the result of pulling the ConfigMaps one container of a
+// workload sees
+type PodPullResult struct {
+    Workload  string `json:"workload"`
+    Namespace string `json:"namespace"`
+    Container string `json:"container"`
+    // Results holds one entry per ConfigMap mounted into the container
+    Results []PullConfigMapResult `json:"items"`
+    // EnvFile is the written EnvFile, nil when the container has no
+    // ConfigMap-sourced environment variables
+    EnvFile *SaveResult `json:"envFile,omitempty"`
+    // Skipped lists optional references whose ConfigMap or key does not exist
+    Skipped []ConfigMapReference `json:"skipped"`
+}
+
+// PullForPod writes the ConfigMap files and environment a container of a pod
+// sees. An empty container selects the pod's first container.
+func (o *Operations) PullForPod(ctx context.Context, namespace, name, container, outputDir string, opts PullOptions) (*PodPullResult, error) {
+    pod, err := o.clientset.CoreV1().Pods(namespace).Get(ctx, name, metav1.GetOptions{})
+    if err != nil {
+        return nil, fmt.Errorf("failed to get pod '%s' in namespace '%s': %w", name, namespace, err)
+    }
+    return o.PullForPodSpec(ctx, namespace, "pod/"+name, &pod.Spec, container, outputDir, opts)
+}
+
+// PullForDeployment writes the ConfigMap files and environment a container
+// of a Deployment's pods sees. An empty container selects the first container.
+func (o *Operations) PullForDeployment(ctx context.Context, namespace, name, container, outputDir string, opts PullOptions) (*PodPullResult, error) {
+    deployment, err := o.clientset.AppsV1().Deployments(namespace).Get(ctx, name, metav1.GetOptions{})
+    if err != nil {
+        return nil, fmt.Errorf("failed to get Deployment '%s' in namespace '%s': %w", name, namespace, err)
+    }
+    return o.PullForPodSpec(ctx, namespace, "deployment/"+name, &deployment.Spec.Template.Spec, container, outputDir, opts)
+}
+
+// PullForPodSpec materializes what container of spec sees from ConfigMaps:
+// mounted volumes are written below outputDir at their mount path, with
+// items remapped and file modes taken from mode and defaultMode, and the
+// environment from envFrom and configMapKeyRef is written to EnvFile. A
+// missing ConfigMap or key fails the pull unless the reference is optional,
+// just like the pod would fail to start.
+func (o *Operations) PullForPodSpec(ctx context.Context, namespace, workload string, spec *corev1.PodSpec, container, outputDir string, opts PullOptions) (*PodPullResult, error) {
+    if opts.As == PullAsManifest {
+        return nil, fmt.Errorf("pulling for a workload only writes files")
+    }
+    run, err := newPullRun(outputDir, opts, LayoutFlat)
+    if err != nil {
+        return nil, err
+    }
+
+    selected, err := findContainer(spec, container)
+    if err != nil {
+        return nil, fmt.Errorf("%s: %w", workload, err)
+    }
+    result := &PodPullResult{
+        Workload:  workload,
+        Namespace: namespace,
+        Container: selected.Name,
+        Skipped:   []ConfigMapReference{},
+    }
+
+    configMaps := map[string]*corev1.ConfigMap{}
+    fetch := func(ref ConfigMapReference) (*corev1.ConfigMap, error) {
+        if configMap, ok := configMaps[ref.Name]; ok {
+            return configMap, nil
+        }
+        configMap, err := o.GetConfigMap(ctx, namespace, ref.Name)
+        if apierrors.IsNotFound(err) {
+            configMap, err = nil, nil
+        }
+        if err != nil {
+            return nil, err
+        }
+        configMaps[ref.Name] = configMap
+        return configMap, nil
+    }
+
+    byVolume := map[string][]ConfigMapReference{}
+    var envRefs []ConfigMapReference
+    for _, ref := range PodConfigMapReferences(spec) {
+        switch {
+        case ref.Volume != "":
+            byVolume[ref.Volume] = append(byVolume[ref.Volume], ref)
+        case ref.Container == selected.Name:
+            envRefs = append(envRefs, ref)
+        }
+    }
+
+    for _, mount := range selected.VolumeMounts {
+        for _, ref := range byVolume[mount.Name] {
+            configMap, err := fetch(ref)
+            if err != nil {
+                return nil, err
+            }
+            if configMap == nil {
+                if !ref.Optional {
+                    return nil, fmt.Errorf("ConfigMap '%s' of volume '%s' not found in namespace '%s'", ref.Name, ref.Volume, namespace)
+                }
+                result.Skipped = append(result.Skipped, ref)
+                continue
+            }
+
+            files, err := volumeFiles(ref, configMap)
+            if err != nil {
+                return nil, err
+            }
+            pulled := run.newResult(KindConfigMap, configMap.Name, namespace)
+            for _, file := range files {
+                rel, ok := mountedPath(mount, file.path)
+                if !ok {
+                    continue
+                }
+                value, _ := liveBytes(configMap, file.key)
+                _, binary := configMap.BinaryData[file.key]
+                run.saveAt(pulled, file.key, rel, value, binary, file.mode)
+            }
+        }
+    }
+
+    env, skipped, err := containerEnv(envRefs, fetch)
+    if err != nil {
+        return nil, err
+    }
+    result.Skipped = append(result.Skipped, skipped...)
+    var envResult *PullConfigMapResult
+    if len(env) > 0 {
+        envResult = run.newSeparateResult("env", selected.Name, namespace)
+        run.saveAt(envResult, EnvFile, EnvFile, formatEnv(env), false, defaultVolumeMode)
+    }
+
+    result.Results, err = run.finish(ctx)
+    if err != nil {
+        return nil, err
+    }
+    if envResult != nil {
+        result.EnvFile = &envResult.SavedFiles[0]
+    }
+    return result, nil
+}
+
+// findContainer returns the container or init container called name, or the
+// first container when name is empty
+func findContainer(spec *corev1.PodSpec, name string) (*corev1.Container, error) {
+    if name == "" {
+        if len(spec.Containers) == 0 {
+            return nil, fmt.Errorf("no containers")
+        }
+        return &spec.Containers[0], nil
+    }
+    for _, containers := range [][]corev1.Container{spec.Containers, spec.InitContainers} {
+        for i := range containers {
+            if containers[i].Name == name {
+                return &containers[i], nil
+            }
+        }
+    }
+    return nil, fmt.Errorf("no container named '%s'", name)
+}
+
+// volumeFile is a key as it appears inside a volume
+type volumeFile struct {
+    key  string
+    path string
+    mode os.FileMode
+}
+
+// volumeFiles lists the files a volume reference projects: every key by its
+// name, or only the listed items at their path
+func volumeFiles(ref ConfigMapReference, configMap *corev1.ConfigMap) ([]volumeFile, error) {
+    mode := os.FileMode(defaultVolumeMode)
+    if ref.DefaultMode != nil {
+        mode = os.FileMode(*ref.DefaultMode) & os.ModePerm
+    }
+
+    if len(ref.Items) == 0 {
+        var files []volumeFile
+        for _, key := range configMapKeys(configMap) {
+            files = append(files, volumeFile{key: key, path: key, mode: mode})
+        }
+        return files, nil
+    }
+
+    var files []volumeFile
+    for _, item := range ref.Items {
+        if !hasKey(configMap, item.Key) {
+            if ref.Optional {
+                continue
+            }
+            return nil, fmt.Errorf("key '%s' of volume '%s' not found in ConfigMap '%s'", item.Key, ref.Volume, configMap.Name)
+        }
+        file := volumeFile{key: item.Key, path: item.Path, mode: mode}
+        if item.Mode != nil {
+            file.mode = os.FileMode(*item.Mode) & os.ModePerm
+        }
+        files = append(files, file)
+    }
+    return files, nil
+}
+
+// mountedPath maps a path inside a volume to where the container sees it,
+// relative to the output directory. With a subPath mount only the files at
+// or below subPath are visible.
+func mountedPath(mount corev1.VolumeMount, volumePath string) (string, bool) {
+    target := strings.TrimPrefix(path.Clean("/"+mount.MountPath), "/")
+    subPath := strings.Trim(mount.SubPath, "/")
+    switch {
+    case subPath == "":
+        return path.Join(target, volumePath), true
+    case volumePath == subPath:
+        return target, true
+    case strings.HasPrefix(volumePath, subPath+"/"):
+        return path.Join(target, strings.TrimPrefix(volumePath, subPath+"/")), true
+    default:
+        return "", false
+    }
+}
+
+// envVar is one variable of a container's environment
+type envVar struct {
+    name  string
+    value string
+}
+
+// containerEnv resolves the ConfigMap-sourced environment of a container.
+// envFrom sources come first and env entries override them, as in the
+// kubelet. Optional references that cannot be resolved are returned as
+// skipped.
+func containerEnv(refs []ConfigMapReference, fetch func(ConfigMapReference) (*corev1.ConfigMap, error)) ([]envVar, []ConfigMapReference, error) {
+    var env []envVar
+    index := map[string]int{}
+    set := func(name, value string) {
+        if i, ok := index[name]; ok {
+            env[i].value = value
+            return
+        }
+        index[name] = len(env)
+        env = append(env, envVar{name: name, value: value})
+    }
+
+    var skipped []ConfigMapReference
+    for _, pass := range []string{RefEnvFrom, RefEnv} {
+        for _, ref := range refs {
+            if ref.Type != pass {
+                continue
+            }
+            configMap, err := fetch(ref)
+            if err != nil {
+                return nil, nil, err
+            }
+            if configMap == nil {
+                if !ref.Optional {
+                    return nil, nil, fmt.Errorf("ConfigMap '%s' of container '%s' not found", ref.Name, ref.Container)
+                }
+                skipped = append(skipped, ref)
+                continue
+            }
+
+            if ref.Type == RefEnvFrom {
+                keys := make([]string, 0, len(configMap.Data))
+                for key := range configMap.Data {
+                    keys = append(keys, key)
+                }
+                sort.Strings(keys)
+                for _, key := range keys {
+                    set(ref.Prefix+key, configMap.Data[key])
+                }
+                continue
+            }
+
+            value, ok := configMap.Data[ref.Key]
+            if !ok {
+                if !ref.Optional {
+                    return nil, nil, fmt.Errorf("key '%s' for variable '%s' not found in ConfigMap '%s'", ref.Key, ref.EnvName, ref.Name)
+                }
+                skipped = append(skipped, ref)
+                continue
+            }
+            set(ref.EnvName, value)
+        }
+    }
+    return env, skipped, nil
+}
+
+// plainEnvValue matches values that need no quoting in an env file
+var plainEnvValue = regexp.MustCompile(`^[A-Za-z0-9_./:@%+,=-]*$`)
+
+// formatEnv renders env as NAME=value lines. Other values are double-quoted
+// with backslash escapes, as understood by dotenv loaders.
+func formatEnv(env []envVar) []byte {
+    var b strings.Builder
+    for _, variable := range env {
+        value := variable.value
+        if !plainEnvValue.MatchString(value) {
+            value = `"` + strings.NewReplacer(
+                `\`, `\\`,
+                `"`, `\"`,
+                "$", `\$`,
+                "`", "\\`",
+                "\n", `\n`,
+            ).Replace(value) + `"`
+        }
+        fmt.Fprintf(&b, "%s=%s\n", variable.name, value)
+    }
+    return []byte(b.String())
+}
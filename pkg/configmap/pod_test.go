@@ -0,0 +1,133 @@
+package configmap
+
+import (
+    "context"
+    "os"
+    "path/filepath"
+    "testing"
+
+    corev1 "k8s.io/api/core/v1"
+    apierrors "k8s.io/apimachinery/pkg/api/errors"
+    "k8s.io/client-go/kubernetes/fake"
+)
+
+func TestPullForDeployment(t *testing.T) {
+    root := t.TempDir()
+    itemMode, defaultMode, optional := int32(0600), int32(0640), true
+
+    volume := configMapVolume("cfg", "app",
+        corev1.KeyToPath{Key: "app.yaml", Path: "conf/app.yaml"},
+        corev1.KeyToPath{Key: "token", Path: "token", Mode: &itemMode},
+    )
+    volume.ConfigMap.DefaultMode = &defaultMode
+    missing := configMapVolume("extra", "missing")
+    missing.ConfigMap.Optional = &optional
+
+    ops := NewOperations(fake.NewClientset(
+        newConfigMap("shop", "app", map[string]string{"app.yaml": "port: 80", "token": "t", "LOG": "debug", "greeting": "hello world"}, nil),
+        newDeployment("shop", "web", corev1.PodSpec{
+            Volumes: []corev1.Volume{volume, missing},
+            Containers: []corev1.Container{{
+                Name: "web",
+                VolumeMounts: []corev1.VolumeMount{
+                    {Name: "cfg", MountPath: "/etc/web"},
+                    {Name: "cfg", MountPath: "/app.yaml", SubPath: "conf/app.yaml"},
+                    {Name: "extra", MountPath: "/etc/extra"},
+                },
+                EnvFrom: []corev1.EnvFromSource{{
+                    Prefix:       "APP_",
+                    ConfigMapRef: &corev1.ConfigMapEnvSource{LocalObjectReference: corev1.LocalObjectReference{Name: "app"}},
+                }},
+                Env: []corev1.EnvVar{configMapEnv("APP_LOG", "app", "greeting")},
+            }},
+        }),
+    ))
+
+    result, err := ops.PullForDeployment(context.Background(), "shop", "web", "", root, PullOptions{})
+    if err != nil {
+        t.Fatalf("PullForDeployment failed: %v", err)
+    }
+    if result.Container != "web" || len(result.Skipped) != 1 || result.Skipped[0].Name != "missing" {
+        t.Errorf("result = %+v, want container web with the optional ConfigMap skipped", result)
+    }
+
+    assertFile(t, filepath.Join(root, "etc", "web", "conf", "app.yaml"), "port: 80")
+    assertFile(t, filepath.Join(root, "app.yaml"), "port: 80")
+    assertFile(t, filepath.Join(root, "etc", "web", "token"), "t")
+    assertMode(t, filepath.Join(root, "etc", "web", "conf", "app.yaml"), 0640)
+    assertMode(t, filepath.Join(root, "etc", "web", "token"), 0600)
+
+    if result.EnvFile == nil || !result.EnvFile.Success {
+        t.Fatalf("EnvFile = %+v, want a written env file", result.EnvFile)
+    }
+    assertFile(t, filepath.Join(root, EnvFile), "APP_LOG=\"hello world\"\nAPP_app.yaml=\"port: 80\"\nAPP_greeting=\"hello world\"\nAPP_token=t\n")
+}
+
+func TestPullForDeploymentAtomicAborted(t *testing.T) {
+    root := t.TempDir()
+    ops := NewOperations(fake.NewClientset(
+        newConfigMap("shop", "app", map[string]string{"conf": "c", "LOG": "debug"}, nil),
+        newConfigMap("shop", "extra", map[string]string{"x": "x"}, nil),
+        newDeployment("shop", "web", corev1.PodSpec{
+            Volumes: []corev1.Volume{configMapVolume("cfg", "app"), configMapVolume("extra", "extra")},
+            Containers: []corev1.Container{{
+                Name: "web",
+                // conf is a file of one volume and the mount point of the other
+                VolumeMounts: []corev1.VolumeMount{
+                    {Name: "cfg", MountPath: "/etc/web"},
+                    {Name: "extra", MountPath: "/etc/web/conf"},
+                },
+                Env: []corev1.EnvVar{configMapEnv("LOG", "app", "LOG")},
+            }},
+        }),
+    ))
+
+    result, err := ops.PullForDeployment(context.Background(), "shop", "web", "", root, PullOptions{Atomic: true})
+    if err != nil {
+        t.Fatalf("PullForDeployment failed: %v", err)
+    }
+    if result.EnvFile == nil || result.EnvFile.Success {
+        t.Errorf("EnvFile = %+v, want the env file reported as not written", result.EnvFile)
+    }
+    for _, pulled := range result.Results {
+        for _, saved := range pulled.SavedFiles {
+            if saved.Success {
+                t.Errorf("%s reported as saved after the atomic update was aborted", saved.Path)
+            }
+        }
+    }
+    if _, err := os.Lstat(filepath.Join(root, EnvFile)); !os.IsNotExist(err) {
+        t.Errorf("%s was published: %v", EnvFile, err)
+    }
+}
+
+func TestPullForDeploymentRequiredMissing(t *testing.T) {
+    ops := NewOperations(fake.NewClientset(
+        newDeployment("shop", "web", corev1.PodSpec{
+            Volumes: []corev1.Volume{configMapVolume("cfg", "missing")},
+            Containers: []corev1.Container{{
+                Name:         "web",
+                VolumeMounts: []corev1.VolumeMount{{Name: "cfg", MountPath: "/etc/web"}},
+            }},
+        }),
+    ))
+
+    if _, err := ops.PullForDeployment(context.Background(), "shop", "web", "", t.TempDir(), PullOptions{}); err == nil {
+        t.Error("PullForDeployment with a missing required ConfigMap succeeded")
+    }
+    if _, err := ops.PullForDeployment(context.Background(), "shop", "missing", "", t.TempDir(), PullOptions{}); !apierrors.IsNotFound(err) {
+        t.Errorf("PullForDeployment of a missing Deployment: error = %v, want not found", err)
+    }
+}
+
+func assertMode(t *testing.T, path string, want os.FileMode) {
+    t.Helper()
+    info, err := os.Stat(path)
+    if err != nil {
+        t.Errorf("stat %s: %v", path, err)
+        return
+    }
+    if got := info.Mode().Perm(); got != want {
+        t.Errorf("%s has mode %o, want %o", path, got, want)
+    }
+}
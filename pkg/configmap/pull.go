@@ -0,0 +1,547 @@
+package configmap
+
+import (
+    "context"
+    "fmt"
+    "os"
+    "path/filepath"
+    "sort"
+    "sync"
+
+    corev1 "k8s.io/api/core/v1"
+)
+
+// Pull output formats
+const (
+    PullAsFiles    = "files"
+    PullAsManifest = "manifest"
+)
+
+// PullOptions controls which objects a pull selects and how they are written.
+// ListOptions only apply to pulls of more than one object.
+type PullOptions struct {
+    ListOptions
+
+    // As is PullAsFiles (one file per key, the default) or PullAsManifest
+    // (one cleaned, re-appliable YAML manifest per ConfigMap)
+    As string
+
+    // SingleFile writes all manifests into one multi-document
+    // ManifestStreamFile at the root of the output directory
+    SingleFile bool
+
+    // Layout maps keys to paths below the output directory. When nil,
+    // single-namespace pulls use LayoutFlat and all-namespace pulls use
+    // LayoutNamespaceKey.
+    Layout *Layout
+
+    // OnCollision is CollisionFail (the default) or CollisionRename
+    OnCollision string
+
+    // Tracker detects collisions across several pulls into the same tree,
+    // e.g. ConfigMaps and Secrets. A fresh tracker is used when nil.
+    Tracker *PathTracker
+
+    // Concurrency is the number of files written in parallel,
+    // DefaultConcurrency when zero
+    Concurrency int
+
+    // Atomic writes the files into a timestamped directory and swaps a
+    // ..data symlink to it, the way the kubelet updates ConfigMap volumes.
+    // The output directory then holds exactly the objects of this pull.
+    Atomic bool
+
+    // Sink receives the files instead of the output directory, e.g. a
+    // TarSink. The caller closes it, so several pulls can share one archive.
+    Sink Sink
+
+    // Backup collects the backup metadata of all-namespace pulls instead of
+    // each of them writing its own BackupMetadataFile. Secrets are only
+    // recorded when it is set.
+    Backup *BackupRecorder
+}
+
+// DefaultConcurrency is the number of files written in parallel by default
+const DefaultConcurrency = 8
+
+// Validate checks the pull format, collision policy and their combinations
+func (p PullOptions) Validate() error {
+    if p.Concurrency < 0 {
+        return fmt.Errorf("invalid concurrency %d (must be at least 1)", p.Concurrency)
+    }
+    if p.Atomic && p.SingleFile {
+        return fmt.Errorf("a single output file cannot be written atomically")
+    }
+    if p.Atomic && p.Sink != nil {
+        return fmt.Errorf("only an output directory can be written atomically")
+    }
+    if p.Atomic && p.Backup != nil {
+        return fmt.Errorf("shared backup metadata cannot be written atomically")
+    }
+
+    switch p.OnCollision {
+    case "", CollisionFail, CollisionRename:
+    default:
+        return fmt.Errorf("invalid collision policy %q (must be one of: %s, %s)", p.OnCollision, CollisionFail, CollisionRename)
+    }
+
+    switch p.As {
+    case "", PullAsFiles:
+        if p.SingleFile {
+            return fmt.Errorf("a single output file is only supported for manifests")
+        }
+        return nil
+    case PullAsManifest:
+        return nil
+    default:
+        return fmt.Errorf("invalid pull format %q (must be one of: %s, %s)", p.As, PullAsFiles, PullAsManifest)
+    }
+}
+
+// pullRun holds the state shared by the objects written during one pull.
+// Objects are added in a fixed order and all paths are planned as they are
+// added, so collisions always resolve the same way; finish then writes the
+// planned files with a bounded pool of workers.
+type pullRun struct {
+    root    string
+    opts    PullOptions
+    sink    Sink
+    layout  *Layout
+    tracker *PathTracker
+    stream  *manifestStream
+    backup  *backupWriter
+
+    results []*PullConfigMapResult
+    pending []pendingWrite
+
+    // separate are results written along with the pull but reported on
+    // their own, such as the env file of a workload pull
+    separate []*PullConfigMapResult
+    backups []backupItem
+}
+
+// backupItem is a ConfigMap or Secret recorded in the backup metadata once
+// written
+type backupItem struct {
+    configMap *corev1.ConfigMap
+    secret    *corev1.Secret
+    result    *PullConfigMapResult
+}
+
+// pendingWrite is a planned file, stored into a result's SavedFiles slot
+type pendingWrite struct {
+    result *PullConfigMapResult
+    index  int
+    rel    string
+    value  []byte
+    perm   os.FileMode
+}
+
+// newPullRun prepares a pull into root, using defaultLayout unless opts
+// carries a layout
+func newPullRun(root string, opts PullOptions, defaultLayout string) (*pullRun, error) {
+    if err := opts.Validate(); err != nil {
+        return nil, err
+    }
+
+    run := &pullRun{
+        root:    root,
+        opts:    opts,
+        sink:    opts.Sink,
+        layout:  opts.Layout,
+        tracker: opts.Tracker,
+    }
+    if run.sink == nil {
+        run.sink = NewDirSink(root)
+    } else if _, ok := run.sink.(*DirSink); !ok {
+        // paths are reported as archive entries, which are added in the
+        // order they were planned
+        run.root = ""
+        run.opts.Concurrency = 1
+    }
+    if run.layout == nil {
+        run.layout = mustParseLayout(defaultLayout)
+    }
+    if run.tracker == nil {
+        run.tracker = NewPathTracker()
+    }
+    if run.opts.Concurrency == 0 {
+        run.opts.Concurrency = DefaultConcurrency
+    }
+    if opts.As == PullAsManifest && opts.SingleFile {
+        run.stream = &manifestStream{}
+    }
+    return run, nil
+}
+
+// recordBackup makes the run record its objects in the backup metadata,
+// opts.Backup if set, and reserves the path of the BackupMetadataFile
+func (r *pullRun) recordBackup() error {
+    r.backup = &backupWriter{}
+    if r.opts.Backup != nil {
+        r.backup = &r.opts.Backup.writer
+    }
+    // Keys that map to the sidecar collide with it instead of overwriting it
+    if _, err := r.tracker.claim(BackupMetadataFile, backupMetadataOwner, "", CollisionFail); err != nil {
+        return fmt.Errorf("cannot write %s: %w", BackupMetadataFile, err)
+    }
+    return nil
+}
+
+// addConfigMap plans the files of a ConfigMap, or adds its manifest to the stream
+func (r *pullRun) addConfigMap(configMap *corev1.ConfigMap) {
+    result := r.newResult(KindConfigMap, configMap.Name, configMap.Namespace)
+
+    if r.opts.As == PullAsManifest {
+        fileName := configMap.Name + ".yaml"
+        document, err := cleanManifest(configMap)
+        switch {
+        case err != nil:
+            result.SavedFiles = append(result.SavedFiles, SaveResult{Key: fileName, Error: err})
+            result.TotalFiles++
+        case r.stream != nil:
+            r.stream.add(document)
+        default:
+            r.save(result, fileName, document, false, 0644)
+        }
+        return
+    }
+
+    for _, key := range configMapKeys(configMap) {
+        value, _ := liveBytes(configMap, key)
+        _, binary := configMap.BinaryData[key]
+        r.save(result, key, value, binary, 0644)
+    }
+
+    if r.backup != nil {
+        r.backups = append(r.backups, backupItem{configMap: configMap, result: result})
+    }
+}
+
+// addConfigMaps adds the non-empty ConfigMaps in namespace and name order
+func (r *pullRun) addConfigMaps(configMaps []corev1.ConfigMap) {
+    sort.Slice(configMaps, func(i, j int) bool {
+        if configMaps[i].Namespace != configMaps[j].Namespace {
+            return configMaps[i].Namespace < configMaps[j].Namespace
+        }
+        return configMaps[i].Name < configMaps[j].Name
+    })
+
+    for i := range configMaps {
+        if len(configMaps[i].Data) == 0 && len(configMaps[i].BinaryData) == 0 {
+            continue // Skip empty ConfigMaps
+        }
+        r.addConfigMap(&configMaps[i])
+    }
+}
+
+// newResult starts the result of one pulled object
+func (r *pullRun) newResult(kind, name, namespace string) *PullConfigMapResult {
+    result := &PullConfigMapResult{
+        Kind:          kind,
+        ConfigMapName: name,
+        Namespace:     namespace,
+        SavedFiles:    []SaveResult{},
+    }
+    r.results = append(r.results, result)
+    return result
+}
+
+// newSeparateResult starts a result that finish does not return, but that
+// is written and, for atomic pulls, aborted along with the others
+func (r *pullRun) newSeparateResult(kind, name, namespace string) *PullConfigMapResult {
+    result := &PullConfigMapResult{
+        Kind:          kind,
+        ConfigMapName: name,
+        Namespace:     namespace,
+        SavedFiles:    []SaveResult{},
+    }
+    r.separate = append(r.separate, result)
+    return result
+}
+
+// save plans writing value to the path the layout assigns to key. Layout,
+// collision and path errors are recorded right away.
+func (r *pullRun) save(result *PullConfigMapResult, key string, value []byte, binary bool, perm os.FileMode) {
+    rel, err := r.layout.Path(LayoutFields{
+        Namespace: result.Namespace,
+        Name:      result.ConfigMapName,
+        Kind:      result.Kind,
+        Key:       key,
+    })
+    if err != nil {
+        result.TotalFiles++
+        result.SavedFiles = append(result.SavedFiles, SaveResult{
+            Key:    key,
+            Path:   filepath.Join(r.root, rel),
+            Binary: binary,
+            Error:  err,
+        })
+        return
+    }
+    r.saveAt(result, key, rel, value, binary, perm)
+}
+
+// saveAt plans writing value to rel below the output directory
+func (r *pullRun) saveAt(result *PullConfigMapResult, key, rel string, value []byte, binary bool, perm os.FileMode) {
+    result.TotalFiles++
+
+    owner := keyOwner(result.Kind, result.Namespace, result.ConfigMapName, key)
+    claimed, err := r.tracker.claim(rel, owner, result.ConfigMapName, r.opts.OnCollision)
+    var outputPath string
+    if err == nil {
+        rel = claimed
+        outputPath, err = r.sink.Resolve(rel)
+    }
+    if err != nil {
+        result.SavedFiles = append(result.SavedFiles, SaveResult{
+            Key:    key,
+            Path:   filepath.Join(r.root, rel),
+            Binary: binary,
+            Error:  err,
+        })
+        return
+    }
+
+    result.SavedFiles = append(result.SavedFiles, SaveResult{Key: key, Path: outputPath, Binary: binary, rel: rel})
+    r.pending = append(r.pending, pendingWrite{
+        result: result,
+        index:  len(result.SavedFiles) - 1,
+        rel:    rel,
+        value:  value,
+        perm:   perm,
+    })
+}
+
+// finish writes the planned files, the manifest stream and backup metadata,
+// and returns the results in the order the objects were added. Once ctx is
+// done no further files are started: the rest are recorded as failed and the
+// results are returned along with an error wrapping ctx's error.
+func (r *pullRun) finish(ctx context.Context) ([]PullConfigMapResult, error) {
+    var metadataErr error
+    if r.opts.Atomic {
+        if err := r.writeAtomic(ctx); err != nil {
+            return nil, err
+        }
+    } else {
+        r.writePending(ctx, r.sink)
+        metadataErr = r.writeMetadata(ctx, r.sink)
+    }
+
+    results := make([]PullConfigMapResult, 0, len(r.results))
+    for _, result := range r.results {
+        results = append(results, *result)
+    }
+
+    if metadataErr != nil {
+        return results, metadataErr
+    }
+    if interrupted := ctx.Err(); interrupted != nil {
+        return results, fmt.Errorf("pull interrupted: %w", interrupted)
+    }
+    return results, nil
+}
+
+// writeMetadata writes the manifest stream and backup metadata to sink once
+// the planned files are written
+func (r *pullRun) writeMetadata(ctx context.Context, sink Sink) error {
+    interrupted := ctx.Err()
+    if r.stream != nil && len(r.results) > 0 && interrupted == nil {
+        r.stream.flush(sink, r.results)
+    }
+    if r.backup == nil {
+        return nil
+    }
+
+    // ConfigMaps an interrupted pull did not get to write in full are
+    // left out, so restoring the tree never touches them
+    for _, item := range r.backups {
+        if interrupted != nil && !writtenCompletely(item.result) {
+            continue
+        }
+        if item.secret != nil {
+            r.backup.addSecret(item.secret, item.result)
+        } else {
+            r.backup.add(item.configMap, item.result)
+        }
+    }
+    if r.opts.Backup != nil {
+        r.opts.Backup.recorded = true
+        return nil
+    }
+    return r.backup.write(sink)
+}
+
+// writePending writes the planned files to sink with at most
+// opts.Concurrency workers. Every write fills its own SavedFiles slot, so no
+// locking is needed. Files that were not started when ctx is done are
+// recorded as failed.
+func (r *pullRun) writePending(ctx context.Context, sink Sink) {
+    jobs := make(chan *pendingWrite)
+    var wg sync.WaitGroup
+    for range min(r.opts.Concurrency, len(r.pending)) {
+        wg.Add(1)
+        go func() {
+            defer wg.Done()
+            for job := range jobs {
+                saved := &job.result.SavedFiles[job.index]
+                saved.Error = sink.WriteFile(job.rel, job.value, job.perm)
+                saved.Success = saved.Error == nil
+            }
+        }()
+    }
+
+    started := 0
+dispatch:
+    for ; started < len(r.pending); started++ {
+        if ctx.Err() != nil {
+            break
+        }
+        select {
+        case jobs <- &r.pending[started]:
+        case <-ctx.Done():
+            break dispatch
+        }
+    }
+    close(jobs)
+    wg.Wait()
+
+    for _, job := range r.pending[started:] {
+        saved := &job.result.SavedFiles[job.index]
+        saved.Error = fmt.Errorf("not written: %w", ctx.Err())
+    }
+    r.pending = nil
+}
+
+// writeAtomic writes the planned files into a new timestamped directory and
+// publishes it. If any file cannot be written, or ctx is done before all of
+// them are, nothing is published and the other files of the pull are
+// reported as failed as well.
+func (r *pullRun) writeAtomic(ctx context.Context) error {
+    stage, err := newAtomicStage(r.root)
+    if err != nil {
+        return err
+    }
+    written := r.pending
+    r.writePending(ctx, NewDirSink(stage))
+
+    var failed error
+    for _, job := range written {
+        saved := &job.result.SavedFiles[job.index]
+        if !saved.Success && failed == nil {
+            failed = saved.Error
+        }
+    }
+    if failed == nil {
+        failed = ctx.Err()
+    }
+    if failed == nil {
+        // The manifest stream and backup metadata are published along with
+        // the files they describe
+        if err := r.writeMetadata(ctx, NewDirSink(stage)); err != nil {
+            os.RemoveAll(stage)
+            return err
+        }
+        if r.stream != nil {
+            failed = r.stream.err
+        }
+    }
+
+    // Paths are reported below root, where the files are published
+    all := append(append([]*PullConfigMapResult{}, r.results...), r.separate...)
+    for _, result := range all {
+        for i := range result.SavedFiles {
+            if saved := &result.SavedFiles[i]; saved.rel != "" {
+                saved.Path = filepath.Join(r.root, saved.rel)
+            }
+        }
+    }
+    if failed == nil {
+        // Once ..data points at stage a failure only affects the visible
+        // links, so stage is kept.
+        return publishAtomic(r.root, stage)
+    }
+
+    os.RemoveAll(stage)
+    for _, result := range all {
+        for i := range result.SavedFiles {
+            if saved := &result.SavedFiles[i]; saved.Success {
+                saved.Success = false
+                saved.Error = fmt.Errorf("atomic update aborted: %w", failed)
+            }
+        }
+    }
+    return nil
+}
+
+// PullConfigMap saves a ConfigMap's data to files
+func (o *Operations) PullConfigMap(ctx context.Context, namespace, name, outputDir string, opts PullOptions) (*PullConfigMapResult, error) {
+    run, err := newPullRun(outputDir, opts, LayoutFlat)
+    if err != nil {
+        return nil, err
+    }
+
+    configMap, err := o.GetConfigMap(ctx, namespace, name)
+    if err != nil {
+        return nil, err
+    }
+
+    run.addConfigMap(configMap)
+    results, err := run.finish(ctx)
+    if len(results) == 0 {
+        return nil, err
+    }
+    return &results[0], err
+}
+
+// PullConfigMaps saves the ConfigMaps in a namespace matching opts
+func (o *Operations) PullConfigMaps(ctx context.Context, namespace, outputDir string, opts PullOptions) ([]PullConfigMapResult, error) {
+    run, err := newPullRun(outputDir, opts, LayoutFlat)
+    if err != nil {
+        return nil, err
+    }
+
+    configMaps, err := o.listConfigMaps(ctx, namespace, opts.ListOptions)
+    if err != nil {
+        return nil, fmt.Errorf("failed to list ConfigMaps in namespace '%s': %w", namespace, err)
+    }
+
+    run.addConfigMaps(configMaps)
+    return run.finish(ctx)
+}
+
+// PullAllConfigMaps saves all ConfigMaps matching opts from all namespaces.
+// The ConfigMaps are fetched with a single paginated cluster-scoped list.
+// With opts.ContinueOnError, failed namespaces and ConfigMaps are recorded in
+// the result instead of being returned as an error.
+// When writing files it also records a BackupMetadataFile at the root of
+// outputDir so the tree can be restored with RestoreConfigMaps.
+// If ctx is cancelled while writing, the result reports the files written so
+// far and is returned along with the error.
+func (o *Operations) PullAllConfigMaps(ctx context.Context, outputDir string, opts PullOptions) (*PullAllResult, error) {
+    run, err := newPullRun(outputDir, opts, LayoutNamespaceKey)
+    if err != nil {
+        return nil, err
+    }
+    if opts.As != PullAsManifest {
+        if err := run.recordBackup(); err != nil {
+            return nil, err
+        }
+    }
+
+    configMaps, failures, err := listAllNamespaces(ctx, o, KindConfigMap, opts.ListOptions, o.listConfigMaps)
+    if err != nil {
+        return nil, err
+    }
+
+    run.addConfigMaps(configMaps)
+    results, err := run.finish(ctx)
+    if results == nil {
+        return nil, err
+    }
+    all, pullErr := newPullAllResult(results, failures, opts)
+    if err != nil {
+        return all, err
+    }
+    return all, pullErr
+}
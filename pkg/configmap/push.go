@@ -0,0 +1,167 @@
+package configmap
+
+import (
+    "bytes"
+    "context"
+    "fmt"
+    "os"
+    "path/filepath"
+    "sort"
+    "strings"
+    "unicode/utf8"
+
+    corev1 "k8s.io/api/core/v1"
+    apierrors "k8s.io/apimachinery/pkg/api/errors"
+    metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+    "k8s.io/apimachinery/pkg/util/validation"
+    corev1ac "k8s.io/client-go/applyconfigurations/core/v1"
+)
+
+// FieldManager is the server-side apply field manager used by kmget
+const FieldManager = "kmget"
+
+// PushResult represents the result of pushing a single file
+type PushResult struct {
+    Key     string `json:"key"`
+    Path    string `json:"path"`
+    Action  string `json:"action,omitempty"`
+    Success bool   `json:"success"`
+    Error   error  `json:"-"`
+    Binary  bool   `json:"binary"`
+}
+
+// PushConfigMapResult represents the result of pushing a directory to a ConfigMap
+type PushConfigMapResult struct {
+    ConfigMapName string       `json:"name"`
+    Namespace     string       `json:"namespace"`
+    Created       bool         `json:"created"`
+    PushedFiles   []PushResult `json:"pushedFiles"`
+    RemovedKeys   []string     `json:"removedKeys,omitempty"`
+    TotalFiles    int          `json:"totalFiles"`
+}
+
+// PushConfigMap creates or updates a ConfigMap from the files in dir using
+// server-side apply. Each regular file becomes a Data key, or a BinaryData key
+// when its content is not valid UTF-8. Hidden files and subdirectories are
+// ignored. Nothing is applied if any file cannot be read or is not a valid key.
+func (o *Operations) PushConfigMap(ctx context.Context, namespace, name, dir string) (*PushConfigMapResult, error) {
+    keys, err := localFiles(dir)
+    if err != nil {
+        return nil, err
+    }
+
+    result := &PushConfigMapResult{
+        ConfigMapName: name,
+        Namespace:     namespace,
+        PushedFiles:   []PushResult{},
+    }
+
+    data := make(map[string]string)
+    binaryData := make(map[string][]byte)
+    failed := 0
+    for _, key := range keys {
+        path := filepath.Join(dir, key)
+        pushResult := PushResult{Key: key, Path: path}
+        if errs := validation.IsConfigMapKey(key); len(errs) > 0 {
+            pushResult.Error = fmt.Errorf("invalid ConfigMap key: %s", strings.Join(errs, "; "))
+        } else if content, err := os.ReadFile(path); err != nil {
+            pushResult.Error = err
+        } else if utf8.Valid(content) {
+            data[key] = string(content)
+        } else {
+            pushResult.Binary = true
+            binaryData[key] = content
+        }
+
+        if pushResult.Error != nil {
+            failed++
+        }
+        result.PushedFiles = append(result.PushedFiles, pushResult)
+        result.TotalFiles++
+    }
+
+    if result.TotalFiles == 0 {
+        return nil, fmt.Errorf("no files to push in directory '%s'", dir)
+    }
+    if failed > 0 {
+        return result, fmt.Errorf("refusing to push ConfigMap '%s': %d file(s) could not be used", name, failed)
+    }
+
+    existing, err := o.clientset.CoreV1().ConfigMaps(namespace).Get(ctx, name, metav1.GetOptions{})
+    if apierrors.IsNotFound(err) {
+        existing = nil
+    } else if err != nil {
+        return nil, fmt.Errorf("failed to get ConfigMap '%s' in namespace '%s': %w", name, namespace, err)
+    }
+
+    applyConfig := corev1ac.ConfigMap(name, namespace).
+        WithData(data).
+        WithBinaryData(binaryData)
+    applied, err := o.clientset.CoreV1().ConfigMaps(namespace).Apply(ctx, applyConfig, metav1.ApplyOptions{
+        FieldManager: FieldManager,
+        Force:        true,
+    })
+    if err != nil {
+        return nil, fmt.Errorf("failed to apply ConfigMap '%s' in namespace '%s': %w", name, namespace, err)
+    }
+
+    result.Created = existing == nil
+    for i := range result.PushedFiles {
+        pushResult := &result.PushedFiles[i]
+        pushResult.Action = keyAction(existing, pushResult.Key, data, binaryData)
+        pushResult.Success = true
+    }
+
+    // Keys previously applied by kmget but missing locally are dropped by the
+    // API server; keys owned by other field managers are left untouched.
+    if existing != nil {
+        for _, key := range configMapKeys(existing) {
+            if !hasKey(applied, key) {
+                result.RemovedKeys = append(result.RemovedKeys, key)
+            }
+        }
+    }
+
+    return result, nil
+}
+
+// keyAction classifies a pushed key against the ConfigMap as it was before the push
+func keyAction(existing *corev1.ConfigMap, key string, data map[string]string, binaryData map[string][]byte) string {
+    if existing == nil || !hasKey(existing, key) {
+        return KeyAdded
+    }
+
+    if value, ok := data[key]; ok {
+        if current, ok := existing.Data[key]; ok && current == value {
+            return KeyUnchanged
+        }
+        return KeyUpdated
+    }
+
+    if current, ok := existing.BinaryData[key]; ok && bytes.Equal(current, binaryData[key]) {
+        return KeyUnchanged
+    }
+    return KeyUpdated
+}
+
+// hasKey reports whether key is present in either Data or BinaryData
+func hasKey(cm *corev1.ConfigMap, key string) bool {
+    if _, ok := cm.Data[key]; ok {
+        return true
+    }
+    _, ok := cm.BinaryData[key]
+    return ok
+}
+
+// configMapKeys returns the sorted Data and BinaryData keys of a ConfigMap
+func configMapKeys(cm *corev1.ConfigMap) []string {
+    keys := make([]string, 0, len(cm.Data)+len(cm.BinaryData))
+    for key := range cm.Data {
+        keys = append(keys, key)
+    }
+    for key := range cm.BinaryData {
+        keys = append(keys, key)
+    }
+    sort.Strings(keys)
+    return keys
+}
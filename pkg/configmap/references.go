@@ -0,0 +1,122 @@
+package configmap
+
+import (
+    corev1 "k8s.io/api/core/v1"
+)
+
+// Ways a pod spec can reference a ConfigMap
+const (
+    RefVolume    = "volume"
+    RefProjected = "projected"
+    RefEnvFrom   = "envFrom"
+    RefEnv       = "env"
+)
+
+// ConfigMapReference is one place a pod spec uses a ConfigMap
+type ConfigMapReference struct {
+    // Name is the referenced ConfigMap
+    Name string `json:"name"`
+    // Type is RefVolume, RefProjected, RefEnvFrom or RefEnv
+    Type     string `json:"type"`
+    Optional bool   `json:"optional,omitempty"`
+
+    // Volume, Items and DefaultMode are set for volume references
+    Volume      string             `json:"volume,omitempty"`
+    Items       []corev1.KeyToPath `json:"items,omitempty"`
+    DefaultMode *int32             `json:"defaultMode,omitempty"`
+
+    // Container is set for environment references, Prefix for envFrom and
+    // Key and EnvName for a single variable
+    Container string `json:"container,omitempty"`
+    Prefix    string `json:"prefix,omitempty"`
+    Key       string `json:"key,omitempty"`
+    EnvName   string `json:"envName,omitempty"`
+}
+
+// PodConfigMapReferences returns every ConfigMap reference in spec: volumes
+// and projected volume sources, then the envFrom and env entries of init,
+// regular and ephemeral containers in spec order
+func PodConfigMapReferences(spec *corev1.PodSpec) []ConfigMapReference {
+    var refs []ConfigMapReference
+    for _, volume := range spec.Volumes {
+        refs = append(refs, volumeReferences(volume)...)
+    }
+    for _, container := range spec.InitContainers {
+        refs = append(refs, envReferences(container.Name, container.EnvFrom, container.Env)...)
+    }
+    for _, container := range spec.Containers {
+        refs = append(refs, envReferences(container.Name, container.EnvFrom, container.Env)...)
+    }
+    for _, container := range spec.EphemeralContainers {
+        refs = append(refs, envReferences(container.Name, container.EnvFrom, container.Env)...)
+    }
+    return refs
+}
+
+// volumeReferences returns the ConfigMaps a volume projects
+func volumeReferences(volume corev1.Volume) []ConfigMapReference {
+    var refs []ConfigMapReference
+    if source := volume.ConfigMap; source != nil {
+        refs = append(refs, ConfigMapReference{
+            Name:        source.Name,
+            Type:        RefVolume,
+            Optional:    isOptional(source.Optional),
+            Volume:      volume.Name,
+            Items:       source.Items,
+            DefaultMode: source.DefaultMode,
+        })
+    }
+    if projected := volume.Projected; projected != nil {
+        for _, source := range projected.Sources {
+            if source.ConfigMap == nil {
+                continue
+            }
+            refs = append(refs, ConfigMapReference{
+                Name:        source.ConfigMap.Name,
+                Type:        RefProjected,
+                Optional:    isOptional(source.ConfigMap.Optional),
+                Volume:      volume.Name,
+                Items:       source.ConfigMap.Items,
+                DefaultMode: projected.DefaultMode,
+            })
+        }
+    }
+    return refs
+}
+
+// envReferences returns the ConfigMaps a container reads environment
+// variables from
+func envReferences(container string, envFrom []corev1.EnvFromSource, env []corev1.EnvVar) []ConfigMapReference {
+    var refs []ConfigMapReference
+    for _, source := range envFrom {
+        if source.ConfigMapRef == nil {
+            continue
+        }
+        refs = append(refs, ConfigMapReference{
+            Name:      source.ConfigMapRef.Name,
+            Type:      RefEnvFrom,
+            Optional:  isOptional(source.ConfigMapRef.Optional),
+            Container: container,
+            Prefix:    source.Prefix,
+        })
+    }
+    for _, variable := range env {
+        if variable.ValueFrom == nil || variable.ValueFrom.ConfigMapKeyRef == nil {
+            continue
+        }
+        ref := variable.ValueFrom.ConfigMapKeyRef
+        refs = append(refs, ConfigMapReference{
+            Name:      ref.Name,
+            Type:      RefEnv,
+            Optional:  isOptional(ref.Optional),
+            Container: container,
+            Key:       ref.Key,
+            EnvName:   variable.Name,
+        })
+    }
+    return refs
+}
+
+func isOptional(optional *bool) bool {
+    return optional != nil && *optional
+}
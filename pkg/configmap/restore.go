@@ -0,0 +1,171 @@
+package configmap
+
+import (
+    "context"
+    "fmt"
+    "os"
+    "path/filepath"
+
+    corev1 "k8s.io/api/core/v1"
+    apierrors "k8s.io/apimachinery/pkg/api/errors"
+    metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Conflict policies applied when a restored ConfigMap already exists
+const (
+    ConflictSkip      = "skip"
+    ConflictOverwrite = "overwrite"
+    ConflictFail      = "fail"
+)
+
+// Actions reported by RestoreConfigMaps
+const (
+    RestoreCreated     = "created"
+    RestoreOverwritten = "overwritten"
+    RestoreSkipped     = "skipped"
+)
+
+// RestoreOptions controls how a backup tree is restored
+type RestoreOptions struct {
+    // DryRun sends every request as a server-side dry run
+    DryRun bool
+
+    // NamespaceMap restores ConfigMaps of the key namespace into the value namespace
+    NamespaceMap map[string]string
+
+    // OnConflict is ConflictSkip (the default), ConflictOverwrite or ConflictFail
+    OnConflict string
+}
+
+// RestoreResult represents the result of restoring a single ConfigMap
+type RestoreResult struct {
+    ConfigMapName   string `json:"name"`
+    Namespace       string `json:"namespace"`
+    SourceNamespace string `json:"sourceNamespace"`
+    Action          string `json:"action,omitempty"`
+    DryRun          bool   `json:"dryRun,omitempty"`
+    TotalKeys       int    `json:"totalKeys"`
+    Error           error  `json:"-"`
+}
+
+// RestoreConfigMaps recreates the ConfigMaps recorded in the BackupMetadataFile
+// of a tree written by PullAllConfigMaps. Failures of individual ConfigMaps are
+// recorded in their result, as are entries marked incomplete, which are never
+// restored; with ConflictFail an existing ConfigMap stops the restore and is
+// returned as an error.
+func (o *Operations) RestoreConfigMaps(ctx context.Context, inputDir string, opts RestoreOptions) ([]RestoreResult, error) {
+    switch opts.OnConflict {
+    case "":
+        opts.OnConflict = ConflictSkip
+    case ConflictSkip, ConflictOverwrite, ConflictFail:
+    default:
+        return nil, fmt.Errorf("invalid conflict policy %q (must be one of: %s, %s, %s)", opts.OnConflict, ConflictSkip, ConflictOverwrite, ConflictFail)
+    }
+
+    metadata, err := ReadBackupMetadata(inputDir)
+    if err != nil {
+        return nil, err
+    }
+
+    var dryRun []string
+    if opts.DryRun {
+        dryRun = []string{metav1.DryRunAll}
+    }
+
+    var results []RestoreResult
+    for _, entry := range metadata.ConfigMaps {
+        namespace := entry.Namespace
+        if mapped, ok := opts.NamespaceMap[namespace]; ok {
+            namespace = mapped
+        }
+
+        result := RestoreResult{
+            ConfigMapName:   entry.Name,
+            Namespace:       namespace,
+            SourceNamespace: entry.Namespace,
+            DryRun:          opts.DryRun,
+            TotalKeys:       len(entry.Keys),
+        }
+
+        if entry.Incomplete {
+            // Restoring part of the keys would drop the others from the
+            // live ConfigMap
+            result.Error = fmt.Errorf("refusing to restore ConfigMap '%s' in namespace '%s': the backup holds only some of its keys", entry.Name, namespace)
+            results = append(results, result)
+            continue
+        }
+
+        configMap, err := loadBackupEntry(inputDir, namespace, entry)
+        if err != nil {
+            result.Error = err
+            results = append(results, result)
+            continue
+        }
+
+        existing, err := o.clientset.CoreV1().ConfigMaps(namespace).Get(ctx, entry.Name, metav1.GetOptions{})
+        switch {
+        case apierrors.IsNotFound(err):
+            _, err = o.clientset.CoreV1().ConfigMaps(namespace).Create(ctx, configMap, metav1.CreateOptions{DryRun: dryRun})
+            result.Action = RestoreCreated
+        case err != nil:
+            // reported below
+        case opts.OnConflict == ConflictSkip:
+            result.Action = RestoreSkipped
+        case opts.OnConflict == ConflictOverwrite:
+            configMap.ResourceVersion = existing.ResourceVersion
+            _, err = o.clientset.CoreV1().ConfigMaps(namespace).Update(ctx, configMap, metav1.UpdateOptions{DryRun: dryRun})
+            result.Action = RestoreOverwritten
+        default:
+            result.Error = fmt.Errorf("ConfigMap '%s' already exists in namespace '%s'", entry.Name, namespace)
+            results = append(results, result)
+            return results, result.Error
+        }
+
+        if err != nil {
+            result.Action = ""
+            result.Error = fmt.Errorf("failed to restore ConfigMap '%s' in namespace '%s': %w", entry.Name, namespace, err)
+        }
+        results = append(results, result)
+    }
+
+    return results, nil
+}
+
+// loadBackupEntry rebuilds a ConfigMap from the files recorded for it
+func loadBackupEntry(root, namespace string, entry BackupEntry) (*corev1.ConfigMap, error) {
+    configMap := &corev1.ConfigMap{
+        ObjectMeta: metav1.ObjectMeta{
+            Name:        entry.Name,
+            Namespace:   namespace,
+            Labels:      entry.Labels,
+            Annotations: entry.Annotations,
+        },
+        Immutable: entry.Immutable,
+    }
+
+    for _, key := range entry.Keys {
+        path, err := resolvePath(root, filepath.FromSlash(key.Path))
+        if err != nil {
+            return nil, fmt.Errorf("refusing to read key '%s' of ConfigMap '%s': %w", key.Key, entry.Name, err)
+        }
+
+        content, err := os.ReadFile(path)
+        if err != nil {
+            return nil, fmt.Errorf("failed to read key '%s' of ConfigMap '%s': %w", key.Key, entry.Name, err)
+        }
+
+        if key.Binary {
+            if configMap.BinaryData == nil {
+                configMap.BinaryData = make(map[string][]byte)
+            }
+            configMap.BinaryData[key.Key] = content
+        } else {
+            if configMap.Data == nil {
+                configMap.Data = make(map[string]string)
+            }
+            configMap.Data[key.Key] = string(content)
+        }
+    }
+
+    return configMap, nil
+}
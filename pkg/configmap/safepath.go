@@ -0,0 +1,85 @@
+package configmap
+
+import (
+    "errors"
+    "fmt"
+    "io/fs"
+    "os"
+    "path/filepath"
+    "strings"
+)
+
+// ErrUnsafePath is returned when a key would be read from or written to a
+// path outside of the output directory
+var ErrUnsafePath = errors.New("unsafe path")
+
+// resolvePath joins rel to root, refusing paths that could escape root.
+// Keys are only restricted by the API server, so keys read from manifests or
+// archives may be absolute, contain '..' segments, or point into a
+// directory that is a symlink to somewhere else.
+func resolvePath(root, rel string) (string, error) {
+    if err := checkRelative(rel); err != nil {
+        return "", err
+    }
+
+    path := filepath.Join(root, rel)
+    if err := checkSymlinks(root, path); err != nil {
+        return "", err
+    }
+    return path, nil
+}
+
+// checkRelative rejects empty and absolute paths and any '..' segment
+func checkRelative(rel string) error {
+    if rel == "" {
+        return fmt.Errorf("%w: empty path", ErrUnsafePath)
+    }
+    if filepath.IsAbs(rel) || strings.HasPrefix(rel, "/") || filepath.VolumeName(rel) != "" {
+        return fmt.Errorf("%w: absolute path '%s'", ErrUnsafePath, rel)
+    }
+
+    segments := strings.FieldsFunc(rel, func(c rune) bool {
+        return c == '/' || os.IsPathSeparator(uint8(c))
+    })
+    for _, segment := range segments {
+        if segment == ".." {
+            return fmt.Errorf("%w: '%s' contains a '..' segment", ErrUnsafePath, rel)
+        }
+    }
+    return nil
+}
+
+// checkSymlinks makes sure the deepest existing part of path, after
+// resolving symlinks, is still inside root. A dangling symlink is refused
+// since writing through it would create its target wherever it points.
+func checkSymlinks(root, path string) error {
+    realRoot, err := filepath.EvalSymlinks(root)
+    if errors.Is(err, fs.ErrNotExist) {
+        return nil // nothing below root exists yet
+    }
+    if err != nil {
+        return fmt.Errorf("failed to resolve '%s': %w", root, err)
+    }
+
+    existing := path
+    for {
+        _, err := os.Lstat(existing)
+        if err == nil {
+            break
+        }
+        if !errors.Is(err, fs.ErrNotExist) {
+            return fmt.Errorf("failed to inspect '%s': %w", existing, err)
+        }
+        existing = filepath.Dir(existing)
+    }
+
+    real, err := filepath.EvalSymlinks(existing)
+    if err != nil {
+        return fmt.Errorf("%w: '%s' cannot be resolved: %v", ErrUnsafePath, existing, err)
+    }
+    rel, err := filepath.Rel(realRoot, real)
+    if err != nil || !filepath.IsLocal(rel) {
+        return fmt.Errorf("%w: '%s' resolves to '%s', outside of '%s'", ErrUnsafePath, existing, real, root)
+    }
+    return nil
+}
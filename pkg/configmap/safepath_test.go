@@ -0,0 +1,173 @@
+package configmap
+
+import (
+    "context"
+    "errors"
+    "os"
+    "path/filepath"
+    "testing"
+
+    corev1 "k8s.io/api/core/v1"
+    metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestResolvePath(t *testing.T) {
+    root := t.TempDir()
+    outside := t.TempDir()
+
+    mustMkdir(t, filepath.Join(root, "inside"))
+    mustSymlink(t, outside, filepath.Join(root, "escape"))
+    mustSymlink(t, filepath.Join(root, "inside"), filepath.Join(root, "alias"))
+    mustSymlink(t, filepath.Join(outside, "target"), filepath.Join(root, "dangling"))
+    mustWriteFile(t, filepath.Join(outside, "secret"))
+    mustSymlink(t, filepath.Join(outside, "secret"), filepath.Join(root, "link"))
+
+    tests := []struct {
+        name   string
+        rel    string
+        unsafe bool
+    }{
+        {name: "plain key", rel: "config.yaml"},
+        {name: "nested key", rel: "ns/cm/config.yaml"},
+        {name: "dotted name", rel: "..config"},
+        {name: "symlink inside root", rel: "alias/config.yaml"},
+        {name: "empty", rel: "", unsafe: true},
+        {name: "absolute", rel: "/etc/passwd", unsafe: true},
+        {name: "parent", rel: "../config.yaml", unsafe: true},
+        {name: "parent only", rel: "..", unsafe: true},
+        {name: "nested parent", rel: "ns/../../config.yaml", unsafe: true},
+        {name: "parent folding back into root", rel: "ns/../config.yaml", unsafe: true},
+        {name: "symlinked directory escaping root", rel: "escape/config.yaml", unsafe: true},
+        {name: "symlinked directory in missing subtree", rel: "escape/a/b/config.yaml", unsafe: true},
+        {name: "symlinked file escaping root", rel: "link", unsafe: true},
+        {name: "dangling symlink", rel: "dangling", unsafe: true},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            path, err := resolvePath(root, tt.rel)
+            if tt.unsafe {
+                if !errors.Is(err, ErrUnsafePath) {
+                    t.Fatalf("resolvePath(%q) = %q, %v; want ErrUnsafePath", tt.rel, path, err)
+                }
+                return
+            }
+            if err != nil {
+                t.Fatalf("resolvePath(%q) failed: %v", tt.rel, err)
+            }
+            if want := filepath.Join(root, tt.rel); path != want {
+                t.Errorf("resolvePath(%q) = %q, want %q", tt.rel, path, want)
+            }
+        })
+    }
+}
+
+func TestResolvePathMissingRoot(t *testing.T) {
+    root := filepath.Join(t.TempDir(), "missing")
+
+    if _, err := resolvePath(root, "ns/config.yaml"); err != nil {
+        t.Errorf("resolvePath below a missing root failed: %v", err)
+    }
+}
+
+func TestLayoutRejectsHostileKeys(t *testing.T) {
+    layout := mustParseLayout(LayoutNamespaceKey)
+
+    for _, key := range []string{"../config.yaml", "../../etc/passwd", "a/../../b", ".."} {
+        if rel, err := layout.Path(LayoutFields{Namespace: "default", Name: "app", Key: key}); !errors.Is(err, ErrUnsafePath) {
+            t.Errorf("Path(%q) = %q, %v; want ErrUnsafePath", key, rel, err)
+        }
+    }
+}
+
+func TestPullRefusesHostileKeys(t *testing.T) {
+    root := t.TempDir()
+    outside := t.TempDir()
+    mustSymlink(t, outside, filepath.Join(root, "escape"))
+
+    run, err := newPullRun(root, PullOptions{}, LayoutFlat)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    configMap := &corev1.ConfigMap{
+        ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "default"},
+        Data: map[string]string{
+            "../outside.yaml":   "a",
+            "/tmp/absolute":     "b",
+            "escape/config.yml": "c",
+            "config.yaml":       "d",
+        },
+    }
+
+    run.addConfigMap(configMap)
+    results, err := run.finish(context.Background())
+    if err != nil {
+        t.Fatal(err)
+    }
+    result := results[0]
+    if result.TotalFiles != 4 {
+        t.Fatalf("TotalFiles = %d, want 4", result.TotalFiles)
+    }
+    for _, saved := range result.SavedFiles {
+        if saved.Key == "config.yaml" {
+            if !saved.Success {
+                t.Errorf("key %q was not saved: %v", saved.Key, saved.Error)
+            }
+            continue
+        }
+        if saved.Success || !errors.Is(saved.Error, ErrUnsafePath) {
+            t.Errorf("key %q: success = %v, error = %v; want ErrUnsafePath", saved.Key, saved.Success, saved.Error)
+        }
+    }
+
+    entries, err := os.ReadDir(outside)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if len(entries) != 0 {
+        t.Errorf("pull wrote %d entries outside of the output directory", len(entries))
+    }
+    if _, err := os.Stat(filepath.Join(filepath.Dir(root), "outside.yaml")); err == nil {
+        t.Error("pull wrote a file above the output directory")
+    }
+}
+
+func TestRestoreRefusesHostilePaths(t *testing.T) {
+    root := t.TempDir()
+    outside := t.TempDir()
+    mustWriteFile(t, filepath.Join(outside, "secret"))
+    mustSymlink(t, outside, filepath.Join(root, "escape"))
+
+    for _, path := range []string{"../secret", filepath.Join(outside, "secret"), "escape/secret"} {
+        entry := BackupEntry{
+            Namespace: "default",
+            Name:      "app",
+            Keys:      []BackupKey{{Key: "secret", Path: filepath.ToSlash(path)}},
+        }
+        if _, err := loadBackupEntry(root, "default", entry); !errors.Is(err, ErrUnsafePath) {
+            t.Errorf("loadBackupEntry with path %q: error = %v, want ErrUnsafePath", path, err)
+        }
+    }
+}
+
+func mustMkdir(t *testing.T, path string) {
+    t.Helper()
+    if err := os.MkdirAll(path, 0755); err != nil {
+        t.Fatal(err)
+    }
+}
+
+func mustSymlink(t *testing.T, target, link string) {
+    t.Helper()
+    if err := os.Symlink(target, link); err != nil {
+        t.Skipf("symlinks not supported: %v", err)
+    }
+}
+
+func mustWriteFile(t *testing.T, path string) {
+    t.Helper()
+    if err := os.WriteFile(path, []byte("data"), 0644); err != nil {
+        t.Fatal(err)
+    }
+}
@@ -0,0 +1,209 @@
+package configmap
+
+import (
+    "context"
+    "fmt"
+    "sort"
+    "unicode/utf8"
+
+    corev1 "k8s.io/api/core/v1"
+    metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// SecretInfo represents Secret information
+type SecretInfo struct {
+    Name      string         `json:"name"`
+    Namespace string         `json:"namespace"`
+    Type      string         `json:"type"`
+    Keys      []string       `json:"keys"`
+    KeySizes  map[string]int `json:"keySizes,omitempty"`
+    KeyCount  int            `json:"keyCount"`
+}
+
+// GetSecret retrieves a specific Secret
+func (o *Operations) GetSecret(ctx context.Context, namespace, name string) (*corev1.Secret, error) {
+    secret, err := o.clientset.CoreV1().Secrets(namespace).Get(ctx, name, metav1.GetOptions{})
+    if err != nil {
+        return nil, fmt.Errorf("failed to get Secret '%s' in namespace '%s': %w", name, namespace, err)
+    }
+    return secret, nil
+}
+
+// ListSecrets lists the Secrets in a namespace matching opts
+func (o *Operations) ListSecrets(ctx context.Context, namespace string, opts ListOptions) ([]SecretInfo, error) {
+    secrets, err := o.listSecrets(ctx, namespace, opts)
+    if err != nil {
+        return nil, fmt.Errorf("failed to list Secrets in namespace '%s': %w", namespace, err)
+    }
+
+    var infos []SecretInfo
+    for i := range secrets {
+        infos = append(infos, secretInfo(&secrets[i]))
+    }
+    return infos, nil
+}
+
+// ListAllSecrets lists Secrets matching opts from all namespaces. With
+// opts.ContinueOnError, namespaces that cannot be listed are returned as
+// failures.
+func (o *Operations) ListAllSecrets(ctx context.Context, opts ListOptions) (map[string][]SecretInfo, []Failure, error) {
+    secrets, failures, err := listAllNamespaces(ctx, o, KindSecret, opts, o.listSecrets)
+    if err != nil {
+        return nil, nil, err
+    }
+
+    result := make(map[string][]SecretInfo)
+    for i := range secrets {
+        info := secretInfo(&secrets[i])
+        result[info.Namespace] = append(result[info.Namespace], info)
+    }
+    return result, failures, nil
+}
+
+// listSecrets fetches the Secrets matching opts page by page
+func (o *Operations) listSecrets(ctx context.Context, namespace string, opts ListOptions) ([]corev1.Secret, error) {
+    var secrets []corev1.Secret
+    err := listPages(ctx, o, opts.toMeta(), o.clientset.CoreV1().Secrets(namespace).List, func(page *corev1.SecretList) {
+        secrets = append(secrets, page.Items...)
+    })
+    if err != nil {
+        return nil, err
+    }
+    return secrets, nil
+}
+
+// secretInfo summarizes a Secret's keys without its values
+func secretInfo(secret *corev1.Secret) SecretInfo {
+    keys := make([]string, 0, len(secret.Data))
+    sizes := make(map[string]int, len(secret.Data))
+    for key, value := range secret.Data {
+        keys = append(keys, key)
+        sizes[key] = len(value)
+    }
+    sort.Strings(keys)
+
+    return SecretInfo{
+        Name:      secret.Name,
+        Namespace: secret.Namespace,
+        Type:      string(secret.Type),
+        Keys:      keys,
+        KeySizes:  sizes,
+        KeyCount:  len(secret.Data),
+    }
+}
+
+// PullSecret saves a Secret's decoded data to files. Secrets can only be
+// pulled as files.
+func (o *Operations) PullSecret(ctx context.Context, namespace, name, outputDir string, opts PullOptions) (*PullConfigMapResult, error) {
+    run, err := newSecretPullRun(outputDir, opts, LayoutFlat)
+    if err != nil {
+        return nil, err
+    }
+
+    secret, err := o.GetSecret(ctx, namespace, name)
+    if err != nil {
+        return nil, err
+    }
+
+    run.addSecret(secret)
+    results, err := run.finish(ctx)
+    if len(results) == 0 {
+        return nil, err
+    }
+    return &results[0], err
+}
+
+// PullSecrets saves the Secrets in a namespace matching opts
+func (o *Operations) PullSecrets(ctx context.Context, namespace, outputDir string, opts PullOptions) ([]PullConfigMapResult, error) {
+    run, err := newSecretPullRun(outputDir, opts, LayoutFlat)
+    if err != nil {
+        return nil, err
+    }
+
+    secrets, err := o.listSecrets(ctx, namespace, opts.ListOptions)
+    if err != nil {
+        return nil, fmt.Errorf("failed to list Secrets in namespace '%s': %w", namespace, err)
+    }
+
+    run.addSecrets(secrets)
+    return run.finish(ctx)
+}
+
+// PullAllSecrets saves all Secrets matching opts from all namespaces. Like
+// PullAllConfigMaps it returns the partial result when ctx is cancelled.
+// Where the Secrets were saved is recorded in opts.Backup, if set.
+func (o *Operations) PullAllSecrets(ctx context.Context, outputDir string, opts PullOptions) (*PullAllResult, error) {
+    run, err := newSecretPullRun(outputDir, opts, LayoutNamespaceKey)
+    if err != nil {
+        return nil, err
+    }
+
+    if opts.Backup != nil {
+        if err := run.recordBackup(); err != nil {
+            return nil, err
+        }
+    }
+
+    secrets, failures, err := listAllNamespaces(ctx, o, KindSecret, opts.ListOptions, o.listSecrets)
+    if err != nil {
+        return nil, err
+    }
+
+    run.addSecrets(secrets)
+    results, err := run.finish(ctx)
+    if results == nil {
+        return nil, err
+    }
+    all, pullErr := newPullAllResult(results, failures, opts)
+    if err != nil {
+        return all, err
+    }
+    return all, pullErr
+}
+
+// newSecretPullRun prepares a pull of Secrets, which only supports files
+func newSecretPullRun(root string, opts PullOptions, defaultLayout string) (*pullRun, error) {
+    if opts.As == PullAsManifest {
+        return nil, fmt.Errorf("manifest output is not supported for Secrets")
+    }
+    return newPullRun(root, opts, defaultLayout)
+}
+
+// addSecrets adds the non-empty Secrets in namespace and name order
+func (r *pullRun) addSecrets(secrets []corev1.Secret) {
+    sort.Slice(secrets, func(i, j int) bool {
+        if secrets[i].Namespace != secrets[j].Namespace {
+            return secrets[i].Namespace < secrets[j].Namespace
+        }
+        return secrets[i].Name < secrets[j].Name
+    })
+
+    for i := range secrets {
+        if len(secrets[i].Data) == 0 {
+            continue // Skip empty Secrets
+        }
+        r.addSecret(&secrets[i])
+    }
+}
+
+// addSecret plans the files of a Secret's decoded values
+func (r *pullRun) addSecret(secret *corev1.Secret) {
+    result := r.newResult(KindSecret, secret.Name, secret.Namespace)
+
+    keys := make([]string, 0, len(secret.Data))
+    for key := range secret.Data {
+        keys = append(keys, key)
+    }
+    sort.Strings(keys)
+
+    // Secret values arrive base64-decoded; anything that is not valid
+    // UTF-8 is reported as binary, mirroring ConfigMap BinaryData.
+    for _, key := range keys {
+        value := secret.Data[key]
+        r.save(result, key, value, !utf8.Valid(value), 0600)
+    }
+
+    if r.backup != nil {
+        r.backups = append(r.backups, backupItem{secret: secret, result: result})
+    }
+}
@@ -0,0 +1,236 @@
+package configmap
+
+import (
+    "archive/tar"
+    "archive/zip"
+    "compress/gzip"
+    "fmt"
+    "io"
+    "os"
+    "path/filepath"
+    "strings"
+    "sync"
+    "time"
+)
+
+// Sink receives the files written by a pull. Paths are relative to the
+// root of the output; a Sink refuses paths that would escape it.
+type Sink interface {
+    // Resolve checks rel and returns the path reported for it
+    Resolve(rel string) (string, error)
+
+    // WriteFile stores data at rel. It may be called concurrently.
+    WriteFile(rel string, data []byte, perm os.FileMode) error
+
+    // Close completes the output. Archives are only valid once closed.
+    Close() error
+}
+
+// DirSink writes files below a directory
+type DirSink struct {
+    root string
+}
+
+// NewDirSink creates a sink writing below root
+func NewDirSink(root string) *DirSink {
+    return &DirSink{root: root}
+}
+
+// Resolve returns the path of rel below the directory
+func (d *DirSink) Resolve(rel string) (string, error) {
+    return resolvePath(d.root, rel)
+}
+
+// WriteFile writes data to rel, creating missing directories
+func (d *DirSink) WriteFile(rel string, data []byte, perm os.FileMode) error {
+    path, err := resolvePath(d.root, rel)
+    if err != nil {
+        return err
+    }
+    if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+        return fmt.Errorf("failed to create output directory: %w", err)
+    }
+    if err := os.WriteFile(path, data, perm); err != nil {
+        return err
+    }
+    // WriteFile applies the umask and keeps the mode of existing files
+    return os.Chmod(path, perm)
+}
+
+// Close does nothing, files are complete once written
+func (d *DirSink) Close() error {
+    return nil
+}
+
+// archiveSink holds what tar and zip archives have in common: entries are
+// written one at a time, and the file the archive goes to, if it was
+// created by CreateArchive, is closed along with it
+type archiveSink struct {
+    mu      sync.Mutex
+    file    io.Closer
+    modTime time.Time
+}
+
+// Resolve returns rel as the name of its archive entry
+func (a *archiveSink) Resolve(rel string) (string, error) {
+    if err := checkRelative(rel); err != nil {
+        return "", err
+    }
+    return filepath.ToSlash(filepath.Clean(rel)), nil
+}
+
+// closeFile closes the archive file after the archive itself was closed
+// with err
+func (a *archiveSink) closeFile(err error) error {
+    if a.file == nil {
+        return err
+    }
+    if closeErr := a.file.Close(); err == nil {
+        err = closeErr
+    }
+    return err
+}
+
+// TarSink writes files into a tar archive, optionally gzip compressed
+type TarSink struct {
+    archiveSink
+    gzip *gzip.Writer
+    tar  *tar.Writer
+}
+
+// NewTarSink creates a sink writing a tar archive to w, gzip compressed
+// when compress is set. Closing the sink does not close w.
+func NewTarSink(w io.Writer, compress bool) *TarSink {
+    t := &TarSink{archiveSink: archiveSink{modTime: time.Now()}}
+    if compress {
+        t.gzip = gzip.NewWriter(w)
+        w = t.gzip
+    }
+    t.tar = tar.NewWriter(w)
+    return t
+}
+
+// WriteFile adds data to the archive as rel
+func (t *TarSink) WriteFile(rel string, data []byte, perm os.FileMode) error {
+    name, err := t.Resolve(rel)
+    if err != nil {
+        return err
+    }
+
+    t.mu.Lock()
+    defer t.mu.Unlock()
+    header := &tar.Header{
+        Typeflag: tar.TypeReg,
+        Name:     name,
+        Mode:     int64(perm.Perm()),
+        Size:     int64(len(data)),
+        ModTime:  t.modTime,
+    }
+    if err := t.tar.WriteHeader(header); err != nil {
+        return fmt.Errorf("failed to add '%s' to the archive: %w", name, err)
+    }
+    if _, err := t.tar.Write(data); err != nil {
+        return fmt.Errorf("failed to add '%s' to the archive: %w", name, err)
+    }
+    return nil
+}
+
+// Close writes the end of the archive
+func (t *TarSink) Close() error {
+    t.mu.Lock()
+    defer t.mu.Unlock()
+    err := t.tar.Close()
+    if t.gzip != nil {
+        if gzipErr := t.gzip.Close(); err == nil {
+            err = gzipErr
+        }
+    }
+    if err != nil {
+        err = fmt.Errorf("failed to complete the archive: %w", err)
+    }
+    return t.closeFile(err)
+}
+
+// ZipSink writes files into a zip archive
+type ZipSink struct {
+    archiveSink
+    zip *zip.Writer
+}
+
+// NewZipSink creates a sink writing a zip archive to w. Closing the sink
+// does not close w.
+func NewZipSink(w io.Writer) *ZipSink {
+    return &ZipSink{
+        archiveSink: archiveSink{modTime: time.Now()},
+        zip:         zip.NewWriter(w),
+    }
+}
+
+// WriteFile adds data to the archive as rel
+func (z *ZipSink) WriteFile(rel string, data []byte, perm os.FileMode) error {
+    name, err := z.Resolve(rel)
+    if err != nil {
+        return err
+    }
+
+    z.mu.Lock()
+    defer z.mu.Unlock()
+    header := &zip.FileHeader{
+        Name:     name,
+        Method:   zip.Deflate,
+        Modified: z.modTime,
+    }
+    header.SetMode(perm.Perm())
+    entry, err := z.zip.CreateHeader(header)
+    if err != nil {
+        return fmt.Errorf("failed to add '%s' to the archive: %w", name, err)
+    }
+    if _, err := entry.Write(data); err != nil {
+        return fmt.Errorf("failed to add '%s' to the archive: %w", name, err)
+    }
+    return nil
+}
+
+// Close writes the central directory of the archive
+func (z *ZipSink) Close() error {
+    z.mu.Lock()
+    defer z.mu.Unlock()
+    err := z.zip.Close()
+    if err != nil {
+        err = fmt.Errorf("failed to complete the archive: %w", err)
+    }
+    return z.closeFile(err)
+}
+
+// CreateArchive creates the archive file path and returns a sink writing
+// to it. The format follows the extension: .tar.gz or .tgz, .tar, or .zip.
+// Closing the sink closes the file.
+func CreateArchive(path string) (Sink, error) {
+    lower := strings.ToLower(path)
+    var format string
+    switch {
+    case strings.HasSuffix(lower, ".tar.gz"), strings.HasSuffix(lower, ".tgz"):
+        format = "tgz"
+    case strings.HasSuffix(lower, ".tar"):
+        format = "tar"
+    case strings.HasSuffix(lower, ".zip"):
+        format = "zip"
+    default:
+        return nil, fmt.Errorf("unknown archive format '%s' (use .tar.gz, .tgz, .tar or .zip)", path)
+    }
+
+    file, err := os.Create(path)
+    if err != nil {
+        return nil, fmt.Errorf("failed to create archive: %w", err)
+    }
+    switch format {
+    case "zip":
+        sink := NewZipSink(file)
+        sink.file = file
+        return sink, nil
+    default:
+        sink := NewTarSink(file, format == "tgz")
+        sink.file = file
+        return sink, nil
+    }
+}
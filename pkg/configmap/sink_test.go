@@ -0,0 +1,105 @@
+package configmap
+
+import (
+    "archive/tar"
+    "archive/zip"
+    "bytes"
+    "context"
+    "io"
+    "path/filepath"
+    "reflect"
+    "testing"
+
+    "k8s.io/client-go/kubernetes/fake"
+)
+
+func newSinkTestOps() *Operations {
+    return NewOperations(fake.NewClientset(
+        newConfigMap("default", "app", map[string]string{"app.yaml": "a"}, nil),
+        newConfigMap("team", "web", map[string]string{"web.yaml": "w"}, nil),
+    ))
+}
+
+func TestPullAllConfigMapsToTar(t *testing.T) {
+    var buf bytes.Buffer
+    sink := NewTarSink(&buf, false)
+    all, err := newSinkTestOps().PullAllConfigMaps(context.Background(), "", PullOptions{Sink: sink})
+    if err != nil {
+        t.Fatalf("PullAllConfigMaps failed: %v", err)
+    }
+    if err := sink.Close(); err != nil {
+        t.Fatalf("Close failed: %v", err)
+    }
+    if got := all.Results[0].SavedFiles[0].Path; got != "default/app.yaml" {
+        t.Errorf("reported path = %q, want the archive entry default/app.yaml", got)
+    }
+
+    entries := map[string]string{}
+    var names []string
+    reader := tar.NewReader(&buf)
+    for {
+        header, err := reader.Next()
+        if err == io.EOF {
+            break
+        }
+        if err != nil {
+            t.Fatalf("reading archive: %v", err)
+        }
+        data, err := io.ReadAll(reader)
+        if err != nil {
+            t.Fatal(err)
+        }
+        names = append(names, header.Name)
+        entries[header.Name] = string(data)
+    }
+    if want := []string{"default/app.yaml", "team/web.yaml", BackupMetadataFile}; !reflect.DeepEqual(names, want) {
+        t.Errorf("entries = %v, want %v", names, want)
+    }
+    if entries["team/web.yaml"] != "w" {
+        t.Errorf("team/web.yaml = %q, want %q", entries["team/web.yaml"], "w")
+    }
+}
+
+func TestCreateArchive(t *testing.T) {
+    path := filepath.Join(t.TempDir(), "backup.zip")
+    sink, err := CreateArchive(path)
+    if err != nil {
+        t.Fatalf("CreateArchive failed: %v", err)
+    }
+    if _, err := newSinkTestOps().PullConfigMap(context.Background(), "default", "app", "", PullOptions{Sink: sink}); err != nil {
+        t.Fatalf("PullConfigMap failed: %v", err)
+    }
+    if err := sink.Close(); err != nil {
+        t.Fatalf("Close failed: %v", err)
+    }
+
+    reader, err := zip.OpenReader(path)
+    if err != nil {
+        t.Fatalf("opening archive: %v", err)
+    }
+    defer reader.Close()
+    if len(reader.File) != 1 || reader.File[0].Name != "app.yaml" {
+        t.Fatalf("entries = %v, want app.yaml", reader.File)
+    }
+
+    if _, err := CreateArchive(filepath.Join(t.TempDir(), "backup.rar")); err == nil {
+        t.Error("CreateArchive accepted an unknown format")
+    }
+}
+
+func TestArchiveSinkRefusesHostilePaths(t *testing.T) {
+    sink := NewTarSink(io.Discard, true)
+    defer sink.Close()
+    for _, rel := range []string{"../escape", "/etc/passwd", "a/../../b"} {
+        if err := sink.WriteFile(rel, []byte("x"), 0644); err == nil {
+            t.Errorf("WriteFile(%q) succeeded", rel)
+        }
+    }
+}
+
+func TestPullOptionsValidateSink(t *testing.T) {
+    opts := PullOptions{Atomic: true, Sink: NewZipSink(io.Discard)}
+    if err := opts.Validate(); err == nil {
+        t.Error("Validate accepted an atomic pull into a sink")
+    }
+}
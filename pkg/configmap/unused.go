@@ -0,0 +1,125 @@
+package configmap
+
+import (
+    "context"
+    "fmt"
+    "sort"
+    "time"
+
+    corev1 "k8s.io/api/core/v1"
+)
+
+// systemConfigMaps are ConfigMaps Kubernetes and common add-ons publish into
+// every namespace for workloads that may or may not read them
+var systemConfigMaps = map[string]bool{
+    "kube-root-ca.crt":         true,
+    "openshift-service-ca.crt": true,
+    "istio-ca-root-cert":       true,
+}
+
+// systemNamespaces hold ConfigMaps read by the control plane rather than by
+// workloads
+var systemNamespaces = map[string]bool{
+    "kube-system":     true,
+    "kube-public":     true,
+    "kube-node-lease": true,
+}
+
+// UnusedOptions controls which ConfigMaps FindUnused reports
+type UnusedOptions struct {
+    ListOptions
+
+    // MinAge leaves out ConfigMaps created less than MinAge ago
+    MinAge time.Duration
+
+    // IncludeSystem also reports well-known system ConfigMaps such as
+    // kube-root-ca.crt and the ConfigMaps of the kube-* namespaces
+    IncludeSystem bool
+}
+
+// UnusedConfigMap is a ConfigMap that no workload references, or one with
+// keys that no workload reads
+type UnusedConfigMap struct {
+    Name              string    `json:"name"`
+    Namespace         string    `json:"namespace"`
+    CreationTimestamp time.Time `json:"creationTimestamp"`
+    // Unused is set when no workload references the ConfigMap at all
+    Unused bool `json:"unused"`
+    // UnusedKeys are the keys no consumer reads, all keys when Unused
+    UnusedKeys []string `json:"unusedKeys"`
+    Consumers  []string `json:"consumers"`
+}
+
+// FindUnused reports the ConfigMaps in namespace, or in all namespaces when
+// empty, that no Pod, Deployment, StatefulSet, DaemonSet, Job or CronJob
+// references, followed by the referenced ones with keys nobody reads
+func (o *Operations) FindUnused(ctx context.Context, namespace string, opts UnusedOptions) ([]UnusedConfigMap, error) {
+    configMaps, err := o.listConfigMaps(ctx, namespace, opts.ListOptions)
+    if err != nil {
+        return nil, fmt.Errorf("failed to list ConfigMaps: %w", err)
+    }
+    consumers, err := o.FindConsumers(ctx, namespace)
+    if err != nil {
+        return nil, err
+    }
+
+    now := o.now()
+    var unused []UnusedConfigMap
+    for i := range configMaps {
+        configMap := &configMaps[i]
+        if !opts.IncludeSystem && isSystemConfigMap(configMap) {
+            continue
+        }
+        if opts.MinAge > 0 && now.Sub(configMap.CreationTimestamp.Time) < opts.MinAge {
+            continue
+        }
+
+        entry := UnusedConfigMap{
+            Name:              configMap.Name,
+            Namespace:         configMap.Namespace,
+            CreationTimestamp: configMap.CreationTimestamp.Time,
+            Consumers:         []string{},
+        }
+        read := map[string]bool{}
+        readsAll := false
+        for _, consumer := range consumers[configMap.Namespace+"/"+configMap.Name] {
+            entry.Consumers = append(entry.Consumers, consumer.String())
+            keys, all := consumer.keys()
+            readsAll = readsAll || all
+            for _, key := range keys {
+                read[key] = true
+            }
+        }
+        if readsAll {
+            continue
+        }
+
+        entry.Unused = len(entry.Consumers) == 0
+        entry.UnusedKeys = []string{}
+        for _, key := range configMapKeys(configMap) {
+            if !read[key] {
+                entry.UnusedKeys = append(entry.UnusedKeys, key)
+            }
+        }
+        if entry.Unused || len(entry.UnusedKeys) > 0 {
+            unused = append(unused, entry)
+        }
+    }
+
+    sort.SliceStable(unused, func(i, j int) bool {
+        if unused[i].Unused != unused[j].Unused {
+            return unused[i].Unused
+        }
+        if unused[i].Namespace != unused[j].Namespace {
+            return unused[i].Namespace < unused[j].Namespace
+        }
+        return unused[i].Name < unused[j].Name
+    })
+    return unused, nil
+}
+
+// isSystemConfigMap reports whether configMap is published or read by
+// Kubernetes itself rather than by workloads
+func isSystemConfigMap(configMap *corev1.ConfigMap) bool {
+    return systemConfigMaps[configMap.Name] || systemNamespaces[configMap.Namespace]
+}
@@ -0,0 +1,296 @@
+package configmap
+
+import (
+    "context"
+    "fmt"
+    "sort"
+
+    appsv1 "k8s.io/api/apps/v1"
+    batchv1 "k8s.io/api/batch/v1"
+    corev1 "k8s.io/api/core/v1"
+    apierrors "k8s.io/apimachinery/pkg/api/errors"
+    metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+    "k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// Consumer is a workload whose pod template references a ConfigMap
+type Consumer struct {
+    Kind       string               `json:"kind"`
+    Name       string               `json:"name"`
+    Namespace  string               `json:"namespace"`
+    References []ConfigMapReference `json:"references"`
+}
+
+// String returns the consumer as kind/name
+func (c Consumer) String() string {
+    return c.Kind + "/" + c.Name
+}
+
+// keys returns the keys the consumer reads, or all when one of its
+// references projects every key
+func (c Consumer) keys() (keys []string, all bool) {
+    for _, ref := range c.References {
+        switch {
+        case ref.Type == RefEnv:
+            keys = append(keys, ref.Key)
+        case len(ref.Items) > 0:
+            for _, item := range ref.Items {
+                keys = append(keys, item.Key)
+            }
+        default:
+            return nil, true
+        }
+    }
+    return keys, false
+}
+
+// KeyUsage lists the consumers of one key
+type KeyUsage struct {
+    Key string `json:"key"`
+    // Exists is false for keys that consumers reference but the ConfigMap
+    // does not have
+    Exists    bool     `json:"exists"`
+    Consumers []string `json:"consumers"`
+}
+
+// UsageResult describes who consumes a ConfigMap
+type UsageResult struct {
+    ConfigMapName string `json:"name"`
+    Namespace     string `json:"namespace"`
+    // Missing is set when the ConfigMap does not exist but is referenced
+    Missing   bool       `json:"missing,omitempty"`
+    Consumers []Consumer `json:"consumers"`
+    Keys      []KeyUsage `json:"keys"`
+}
+
+// workload is an object with a pod template
+type workload struct {
+    kind      string
+    name      string
+    namespace string
+    spec      *corev1.PodSpec
+}
+
+// Usage finds the workloads in namespace that reference the ConfigMap name
+// and which of its keys each of them reads
+func (o *Operations) Usage(ctx context.Context, namespace, name string) (*UsageResult, error) {
+    result := &UsageResult{ConfigMapName: name, Namespace: namespace, Consumers: []Consumer{}, Keys: []KeyUsage{}}
+
+    configMap, err := o.GetConfigMap(ctx, namespace, name)
+    switch {
+    case apierrors.IsNotFound(err):
+        result.Missing = true
+        configMap = &corev1.ConfigMap{}
+    case err != nil:
+        return nil, err
+    }
+
+    consumers, err := o.FindConsumers(ctx, namespace)
+    if err != nil {
+        return nil, err
+    }
+    result.Consumers = append(result.Consumers, consumers[namespace+"/"+name]...)
+
+    usage := map[string][]string{}
+    for _, key := range configMapKeys(configMap) {
+        usage[key] = []string{}
+    }
+    for _, consumer := range result.Consumers {
+        keys, all := consumer.keys()
+        if all {
+            keys = configMapKeys(configMap)
+        }
+        for _, key := range keys {
+            users := usage[key]
+            if len(users) == 0 || users[len(users)-1] != consumer.String() {
+                usage[key] = append(users, consumer.String())
+            }
+        }
+    }
+
+    for _, key := range sortedKeys(usage) {
+        result.Keys = append(result.Keys, KeyUsage{
+            Key:       key,
+            Exists:    hasKey(configMap, key),
+            Consumers: usage[key],
+        })
+    }
+    return result, nil
+}
+
+// FindConsumers scans the Pods, Deployments, StatefulSets, DaemonSets, Jobs
+// and CronJobs of namespace, or of all namespaces when empty, and returns
+// their ConfigMap references by namespace/name. Pods and Jobs created by one
+// of these workloads are left out in favour of it; Pods of other controllers,
+// such as a bare ReplicaSet, are reported themselves.
+func (o *Operations) FindConsumers(ctx context.Context, namespace string) (map[string][]Consumer, error) {
+    workloads, err := o.listWorkloads(ctx, namespace)
+    if err != nil {
+        return nil, err
+    }
+
+    consumers := map[string][]Consumer{}
+    for _, w := range workloads {
+        byName := map[string]*Consumer{}
+        var order []string
+        for _, ref := range PodConfigMapReferences(w.spec) {
+            consumer, ok := byName[ref.Name]
+            if !ok {
+                consumer = &Consumer{Kind: w.kind, Name: w.name, Namespace: w.namespace}
+                byName[ref.Name] = consumer
+                order = append(order, ref.Name)
+            }
+            consumer.References = append(consumer.References, ref)
+        }
+        for _, name := range order {
+            id := w.namespace + "/" + name
+            consumers[id] = append(consumers[id], *byName[name])
+        }
+    }
+    return consumers, nil
+}
+
+// listWorkloads lists every object with a pod template in namespace, page by
+// page
+func (o *Operations) listWorkloads(ctx context.Context, namespace string) ([]workload, error) {
+    var pods []corev1.Pod
+    err := listPages(ctx, o, metav1.ListOptions{}, o.clientset.CoreV1().Pods(namespace).List, func(page *corev1.PodList) {
+        pods = append(pods, page.Items...)
+    })
+    if err != nil {
+        return nil, fmt.Errorf("failed to list Pods: %w", err)
+    }
+    var replicaSets []appsv1.ReplicaSet
+    err = listPages(ctx, o, metav1.ListOptions{}, o.clientset.AppsV1().ReplicaSets(namespace).List, func(page *appsv1.ReplicaSetList) {
+        replicaSets = append(replicaSets, page.Items...)
+    })
+    if err != nil {
+        return nil, fmt.Errorf("failed to list ReplicaSets: %w", err)
+    }
+    var deployments []appsv1.Deployment
+    err = listPages(ctx, o, metav1.ListOptions{}, o.clientset.AppsV1().Deployments(namespace).List, func(page *appsv1.DeploymentList) {
+        deployments = append(deployments, page.Items...)
+    })
+    if err != nil {
+        return nil, fmt.Errorf("failed to list Deployments: %w", err)
+    }
+    var statefulSets []appsv1.StatefulSet
+    err = listPages(ctx, o, metav1.ListOptions{}, o.clientset.AppsV1().StatefulSets(namespace).List, func(page *appsv1.StatefulSetList) {
+        statefulSets = append(statefulSets, page.Items...)
+    })
+    if err != nil {
+        return nil, fmt.Errorf("failed to list StatefulSets: %w", err)
+    }
+    var daemonSets []appsv1.DaemonSet
+    err = listPages(ctx, o, metav1.ListOptions{}, o.clientset.AppsV1().DaemonSets(namespace).List, func(page *appsv1.DaemonSetList) {
+        daemonSets = append(daemonSets, page.Items...)
+    })
+    if err != nil {
+        return nil, fmt.Errorf("failed to list DaemonSets: %w", err)
+    }
+    var jobs []batchv1.Job
+    err = listPages(ctx, o, metav1.ListOptions{}, o.clientset.BatchV1().Jobs(namespace).List, func(page *batchv1.JobList) {
+        jobs = append(jobs, page.Items...)
+    })
+    if err != nil {
+        return nil, fmt.Errorf("failed to list Jobs: %w", err)
+    }
+    var cronJobs []batchv1.CronJob
+    err = listPages(ctx, o, metav1.ListOptions{}, o.clientset.BatchV1().CronJobs(namespace).List, func(page *batchv1.CronJobList) {
+        cronJobs = append(cronJobs, page.Items...)
+    })
+    if err != nil {
+        return nil, fmt.Errorf("failed to list CronJobs: %w", err)
+    }
+
+    // Pods and Jobs are left out in favour of their controller only when
+    // the controller is reported itself. A Job always is, directly or
+    // through its CronJob; a ReplicaSet only when it belongs to a
+    // Deployment. Pods of a bare ReplicaSet or of an operator's custom
+    // resource are reported as Pods.
+    reported := controllers{}
+    for i := range deployments {
+        reported.add(appsv1.SchemeGroupVersion.WithKind("Deployment").GroupKind(), &deployments[i])
+    }
+    for i := range statefulSets {
+        reported.add(appsv1.SchemeGroupVersion.WithKind("StatefulSet").GroupKind(), &statefulSets[i])
+    }
+    for i := range daemonSets {
+        reported.add(appsv1.SchemeGroupVersion.WithKind("DaemonSet").GroupKind(), &daemonSets[i])
+    }
+    for i := range cronJobs {
+        reported.add(batchv1.SchemeGroupVersion.WithKind("CronJob").GroupKind(), &cronJobs[i])
+    }
+    for i := range jobs {
+        reported.add(batchv1.SchemeGroupVersion.WithKind("Job").GroupKind(), &jobs[i])
+    }
+    for i := range replicaSets {
+        if reported.controls(&replicaSets[i]) {
+            reported.add(appsv1.SchemeGroupVersion.WithKind("ReplicaSet").GroupKind(), &replicaSets[i])
+        }
+    }
+
+    var workloads []workload
+    for i := range pods {
+        pod := &pods[i]
+        if !reported.controls(pod) {
+            workloads = append(workloads, workload{"Pod", pod.Name, pod.Namespace, &pod.Spec})
+        }
+    }
+    for i := range deployments {
+        deployment := &deployments[i]
+        workloads = append(workloads, workload{"Deployment", deployment.Name, deployment.Namespace, &deployment.Spec.Template.Spec})
+    }
+    for i := range statefulSets {
+        statefulSet := &statefulSets[i]
+        workloads = append(workloads, workload{"StatefulSet", statefulSet.Name, statefulSet.Namespace, &statefulSet.Spec.Template.Spec})
+    }
+    for i := range daemonSets {
+        daemonSet := &daemonSets[i]
+        workloads = append(workloads, workload{"DaemonSet", daemonSet.Name, daemonSet.Namespace, &daemonSet.Spec.Template.Spec})
+    }
+    for i := range jobs {
+        job := &jobs[i]
+        if !reported.controls(job) {
+            workloads = append(workloads, workload{"Job", job.Name, job.Namespace, &job.Spec.Template.Spec})
+        }
+    }
+    for i := range cronJobs {
+        cronJob := &cronJobs[i]
+        workloads = append(workloads, workload{"CronJob", cronJob.Name, cronJob.Namespace, &cronJob.Spec.JobTemplate.Spec.Template.Spec})
+    }
+
+    return workloads, nil
+}
+
+// controllers is a set of objects identified by group, kind, namespace and name
+type controllers map[string]bool
+
+func controllerID(groupKind schema.GroupKind, namespace, name string) string {
+    return groupKind.String() + "/" + namespace + "/" + name
+}
+
+// add records object as a controller of kind groupKind
+func (c controllers) add(groupKind schema.GroupKind, object metav1.Object) {
+    c[controllerID(groupKind, object.GetNamespace(), object.GetName())] = true
+}
+
+// controls reports whether the controller of object is in the set
+func (c controllers) controls(object metav1.Object) bool {
+    owner := metav1.GetControllerOf(object)
+    if owner == nil {
+        return false
+    }
+    groupKind := schema.FromAPIVersionAndKind(owner.APIVersion, owner.Kind).GroupKind()
+    return c[controllerID(groupKind, object.GetNamespace(), owner.Name)]
+}
+
+// sortedKeys returns the keys of m in order
+func sortedKeys[T any](m map[string]T) []string {
+    keys := make([]string, 0, len(m))
+    for key := range m {
+        keys = append(keys, key)
+    }
+    sort.Strings(keys)
+    return keys
+}
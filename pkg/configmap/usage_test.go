@@ -0,0 +1,224 @@
+package configmap
+
+import (
+    "context"
+    "reflect"
+    "testing"
+    "time"
+
+    appsv1 "k8s.io/api/apps/v1"
+    batchv1 "k8s.io/api/batch/v1"
+    corev1 "k8s.io/api/core/v1"
+    metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+    "k8s.io/apimachinery/pkg/runtime"
+    "k8s.io/client-go/kubernetes/fake"
+    k8stesting "k8s.io/client-go/testing"
+)
+
+func TestUsage(t *testing.T) {
+    ops := NewOperations(fake.NewClientset(
+        newConfigMap("shop", "app", map[string]string{"a.yaml": "a", "b.yaml": "b", "LOG": "debug"}, nil),
+        newDeployment("shop", "web", corev1.PodSpec{
+            Volumes: []corev1.Volume{configMapVolume("cfg", "app", corev1.KeyToPath{Key: "a.yaml", Path: "a.yaml"}, corev1.KeyToPath{Key: "gone", Path: "gone"})},
+            Containers: []corev1.Container{{
+                Name: "web",
+                Env:  []corev1.EnvVar{configMapEnv("LOG_LEVEL", "app", "LOG")},
+            }},
+        }),
+        newCronJob("shop", "report", corev1.PodSpec{
+            Containers: []corev1.Container{{
+                Name: "report",
+                Env:  []corev1.EnvVar{configMapEnv("LOG_LEVEL", "app", "LOG")},
+            }},
+        }),
+        // created by the Deployment's ReplicaSet, so reported as the Deployment
+        newReplicaSet("shop", "web-1-rs", "web"),
+        newOwnedPod("shop", "web-1", corev1.PodSpec{
+            Volumes:    []corev1.Volume{configMapVolume("cfg", "app")},
+            Containers: []corev1.Container{{Name: "web"}},
+        }),
+        // created by a ReplicaSet without a Deployment, so reported itself
+        newReplicaSet("shop", "legacy-1-rs", ""),
+        newOwnedPod("shop", "legacy-1", corev1.PodSpec{
+            Containers: []corev1.Container{{
+                Name: "legacy",
+                Env:  []corev1.EnvVar{configMapEnv("LOG_LEVEL", "app", "LOG")},
+            }},
+        }),
+    ))
+
+    result, err := ops.Usage(context.Background(), "shop", "app")
+    if err != nil {
+        t.Fatalf("Usage failed: %v", err)
+    }
+
+    var consumers []string
+    for _, consumer := range result.Consumers {
+        consumers = append(consumers, consumer.String())
+    }
+    if want := []string{"Pod/legacy-1", "Deployment/web", "CronJob/report"}; !reflect.DeepEqual(consumers, want) {
+        t.Errorf("consumers = %v, want %v", consumers, want)
+    }
+
+    want := []KeyUsage{
+        {Key: "LOG", Exists: true, Consumers: []string{"Pod/legacy-1", "Deployment/web", "CronJob/report"}},
+        {Key: "a.yaml", Exists: true, Consumers: []string{"Deployment/web"}},
+        {Key: "b.yaml", Exists: true, Consumers: []string{}},
+        {Key: "gone", Exists: false, Consumers: []string{"Deployment/web"}},
+    }
+    if !reflect.DeepEqual(result.Keys, want) {
+        t.Errorf("keys = %+v, want %+v", result.Keys, want)
+    }
+}
+
+func TestFindUnused(t *testing.T) {
+    now := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+    old := newConfigMap("shop", "old", map[string]string{"a": "a"}, nil)
+    old.CreationTimestamp = metav1.NewTime(now.Add(-60 * 24 * time.Hour))
+    fresh := newConfigMap("shop", "fresh", map[string]string{"a": "a"}, nil)
+    fresh.CreationTimestamp = metav1.NewTime(now.Add(-time.Hour))
+    partly := newConfigMap("shop", "partly", map[string]string{"used": "u", "stale": "s"}, nil)
+    partly.CreationTimestamp = old.CreationTimestamp
+    whole := newConfigMap("shop", "whole", map[string]string{"a": "a"}, nil)
+    whole.CreationTimestamp = old.CreationTimestamp
+    rootCA := newConfigMap("shop", "kube-root-ca.crt", map[string]string{"ca.crt": "ca"}, nil)
+    rootCA.CreationTimestamp = old.CreationTimestamp
+    legacy := newConfigMap("shop", "legacy", map[string]string{"a": "a"}, nil)
+    legacy.CreationTimestamp = old.CreationTimestamp
+
+    ops := NewOperations(fake.NewClientset(
+        old, fresh, partly, whole, rootCA, legacy,
+        newDeployment("shop", "web", corev1.PodSpec{
+            Volumes: []corev1.Volume{
+                configMapVolume("partly", "partly", corev1.KeyToPath{Key: "used", Path: "used"}),
+                configMapVolume("whole", "whole"),
+            },
+            Containers: []corev1.Container{{Name: "web"}},
+        }),
+        // a Pod of a ReplicaSet without a Deployment still uses legacy
+        newReplicaSet("shop", "legacy-1-rs", ""),
+        newOwnedPod("shop", "legacy-1", corev1.PodSpec{
+            Volumes:    []corev1.Volume{configMapVolume("cfg", "legacy")},
+            Containers: []corev1.Container{{Name: "legacy"}},
+        }),
+    ), WithClock(func() time.Time { return now }))
+
+    tests := []struct {
+        name string
+        opts UnusedOptions
+        want []string
+    }{
+        {name: "defaults", want: []string{"fresh", "old", "partly"}},
+        {name: "min age", opts: UnusedOptions{MinAge: 30 * 24 * time.Hour}, want: []string{"old", "partly"}},
+        {name: "include system", opts: UnusedOptions{IncludeSystem: true}, want: []string{"fresh", "kube-root-ca.crt", "old", "partly"}},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            unused, err := ops.FindUnused(context.Background(), "shop", tt.opts)
+            if err != nil {
+                t.Fatalf("FindUnused failed: %v", err)
+            }
+            var names []string
+            for _, cm := range unused {
+                names = append(names, cm.Name)
+                if cm.Name == "partly" && (cm.Unused || !reflect.DeepEqual(cm.UnusedKeys, []string{"stale"})) {
+                    t.Errorf("partly = %+v, want used with unused key stale", cm)
+                }
+            }
+            if !reflect.DeepEqual(names, tt.want) {
+                t.Errorf("FindUnused = %v, want %v", names, tt.want)
+            }
+        })
+    }
+}
+
+func TestFindConsumersPageSize(t *testing.T) {
+    clientset := fake.NewClientset()
+    limits := map[string]int64{}
+    clientset.PrependReactor("list", "*", func(action k8stesting.Action) (bool, runtime.Object, error) {
+        limits[action.GetResource().Resource] = action.(k8stesting.ListActionImpl).GetListOptions().Limit
+        return false, nil, nil
+    })
+
+    if _, err := NewOperations(clientset, WithPageSize(50)).FindConsumers(context.Background(), "shop"); err != nil {
+        t.Fatal(err)
+    }
+    want := map[string]int64{
+        "pods": 50, "replicasets": 50, "deployments": 50, "statefulsets": 50,
+        "daemonsets": 50, "jobs": 50, "cronjobs": 50,
+    }
+    if !reflect.DeepEqual(limits, want) {
+        t.Errorf("list limits = %v, want %v", limits, want)
+    }
+}
+
+func configMapVolume(name, configMap string, items ...corev1.KeyToPath) corev1.Volume {
+    return corev1.Volume{
+        Name: name,
+        VolumeSource: corev1.VolumeSource{ConfigMap: &corev1.ConfigMapVolumeSource{
+            LocalObjectReference: corev1.LocalObjectReference{Name: configMap},
+            Items:                items,
+        }},
+    }
+}
+
+func configMapEnv(name, configMap, key string) corev1.EnvVar {
+    return corev1.EnvVar{
+        Name: name,
+        ValueFrom: &corev1.EnvVarSource{ConfigMapKeyRef: &corev1.ConfigMapKeySelector{
+            LocalObjectReference: corev1.LocalObjectReference{Name: configMap},
+            Key:                  key,
+        }},
+    }
+}
+
+func newDeployment(namespace, name string, spec corev1.PodSpec) *appsv1.Deployment {
+    return &appsv1.Deployment{
+        ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+        Spec:       appsv1.DeploymentSpec{Template: corev1.PodTemplateSpec{Spec: spec}},
+    }
+}
+
+func newCronJob(namespace, name string, spec corev1.PodSpec) *batchv1.CronJob {
+    return &batchv1.CronJob{
+        ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+        Spec: batchv1.CronJobSpec{JobTemplate: batchv1.JobTemplateSpec{
+            Spec: batchv1.JobSpec{Template: corev1.PodTemplateSpec{Spec: spec}},
+        }},
+    }
+}
+
+// newReplicaSet creates a ReplicaSet controlled by deployment, or a bare one
+// when deployment is empty
+func newReplicaSet(namespace, name, deployment string) *appsv1.ReplicaSet {
+    replicaSet := &appsv1.ReplicaSet{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace}}
+    if deployment != "" {
+        controller := true
+        replicaSet.OwnerReferences = []metav1.OwnerReference{{
+            APIVersion: "apps/v1",
+            Kind:       "Deployment",
+            Name:       deployment,
+            Controller: &controller,
+        }}
+    }
+    return replicaSet
+}
+
+// newOwnedPod creates a Pod controlled by the ReplicaSet <name>-rs
+func newOwnedPod(namespace, name string, spec corev1.PodSpec) *corev1.Pod {
+    controller := true
+    return &corev1.Pod{
+        ObjectMeta: metav1.ObjectMeta{
+            Name:      name,
+            Namespace: namespace,
+            OwnerReferences: []metav1.OwnerReference{{
+                APIVersion: "apps/v1",
+                Kind:       "ReplicaSet",
+                Name:       name + "-rs",
+                Controller: &controller,
+            }},
+        },
+        Spec: spec,
+    }
+}
@@ -0,0 +1,234 @@
+package configmap
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "os"
+    "path/filepath"
+
+    corev1 "k8s.io/api/core/v1"
+    metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+    "k8s.io/apimachinery/pkg/fields"
+    "k8s.io/client-go/informers"
+    "k8s.io/client-go/tools/cache"
+)
+
+// Watch event types
+const (
+    WatchSynced  = "synced"
+    WatchDeleted = "deleted"
+)
+
+// WatchEvent describes one sync of a watched ConfigMap to local files
+type WatchEvent struct {
+    Type   string               `json:"type"`
+    Result *PullConfigMapResult `json:"result"`
+    // Removed lists the files deleted because their key is gone
+    Removed []string `json:"removed"`
+    // Error reports files that could not be removed
+    Error error `json:"-"`
+}
+
+// watcher keeps the local files of the watched ConfigMaps in sync. The
+// informer calls its handlers one at a time, so it needs no locking.
+type watcher struct {
+    ctx           context.Context
+    root          string
+    opts          PullOptions
+    defaultLayout string
+    tracker       *PathTracker
+    store         cache.Store
+    written       map[string][]string // namespace/name -> paths written for it
+    onSync        func(WatchEvent)
+}
+
+// WatchConfigMaps pulls the ConfigMap called name, or the ConfigMaps
+// matching opts when name is empty, and rewrites the local files whenever
+// they change until ctx is cancelled. A named ConfigMap has to match the
+// selectors of opts as well. Files of keys that disappear are deleted. An
+// empty namespace watches all namespaces. onSync is called after every sync.
+func (o *Operations) WatchConfigMaps(ctx context.Context, namespace, name, outputDir string, opts PullOptions, onSync func(WatchEvent)) error {
+    if opts.SingleFile {
+        return fmt.Errorf("a single output file cannot be watched")
+    }
+    if opts.Sink != nil {
+        return fmt.Errorf("only an output directory can be watched")
+    }
+    defaultLayout := LayoutFlat
+    if namespace == metav1.NamespaceAll {
+        defaultLayout = LayoutNamespaceKey
+    }
+    if _, err := newPullRun(outputDir, opts, defaultLayout); err != nil {
+        return err
+    }
+
+    w := &watcher{
+        ctx:           ctx,
+        root:          outputDir,
+        opts:          opts,
+        defaultLayout: defaultLayout,
+        tracker:       opts.Tracker,
+        written:       map[string][]string{},
+        onSync:        onSync,
+    }
+    if w.tracker == nil {
+        w.tracker = NewPathTracker()
+    }
+    w.opts.Tracker = w.tracker
+
+    factory := informers.NewSharedInformerFactoryWithOptions(o.clientset, 0,
+        informers.WithNamespace(namespace),
+        informers.WithTweakListOptions(func(listOptions *metav1.ListOptions) {
+            listOptions.LabelSelector = opts.LabelSelector
+            listOptions.FieldSelector = opts.FieldSelector
+            if name != "" {
+                // both have to match, the name narrows the user's selector
+                byName := fields.OneTermEqualSelector("metadata.name", name).String()
+                if opts.FieldSelector == "" {
+                    listOptions.FieldSelector = byName
+                } else {
+                    listOptions.FieldSelector = opts.FieldSelector + "," + byName
+                }
+            }
+        }))
+    informer := factory.Core().V1().ConfigMaps().Informer()
+    w.store = informer.GetStore()
+    _, err := informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+        AddFunc: func(obj interface{}) {
+            w.sync(obj.(*corev1.ConfigMap))
+        },
+        UpdateFunc: func(oldObj, newObj interface{}) {
+            previous, current := oldObj.(*corev1.ConfigMap), newObj.(*corev1.ConfigMap)
+            if previous.ResourceVersion == current.ResourceVersion {
+                return
+            }
+            w.sync(current)
+        },
+        DeleteFunc: func(obj interface{}) {
+            if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+                obj = tombstone.Obj
+            }
+            if configMap, ok := obj.(*corev1.ConfigMap); ok {
+                w.remove(configMap)
+            }
+        },
+    })
+    if err != nil {
+        return fmt.Errorf("failed to watch ConfigMaps: %w", err)
+    }
+
+    factory.Start(ctx.Done())
+    defer factory.Shutdown()
+    if !cache.WaitForCacheSync(ctx.Done(), informer.HasSynced) {
+        if err := ctx.Err(); err != nil {
+            return nil
+        }
+        return fmt.Errorf("failed to sync ConfigMaps in namespace '%s'", namespace)
+    }
+
+    <-ctx.Done()
+    return nil
+}
+
+// sync writes the current keys of configMap and deletes the files of keys
+// written before that are gone now
+func (w *watcher) sync(configMap *corev1.ConfigMap) {
+    result, err := w.pull(configMap)
+
+    var written []string
+    current := map[string]bool{}
+    for _, saved := range result.SavedFiles {
+        if saved.Success {
+            written = append(written, saved.Path)
+            current[saved.Path] = true
+        }
+    }
+
+    id := configMap.Namespace + "/" + configMap.Name
+    var stale []string
+    for _, path := range w.written[id] {
+        if !current[path] {
+            stale = append(stale, path)
+        }
+    }
+    w.written[id] = written
+
+    removed, removeErr := w.removeFiles(stale)
+    w.onSync(WatchEvent{Type: WatchSynced, Result: result, Removed: removed, Error: errors.Join(err, removeErr)})
+}
+
+// remove deletes every file written for a deleted ConfigMap
+func (w *watcher) remove(configMap *corev1.ConfigMap) {
+    id := configMap.Namespace + "/" + configMap.Name
+    stale := w.written[id]
+    delete(w.written, id)
+
+    var err error
+    if w.opts.Atomic {
+        _, err = w.pull(configMap)
+    }
+    removed, removeErr := w.removeFiles(stale)
+    w.onSync(WatchEvent{
+        Type:    WatchDeleted,
+        Result:  emptyResult(configMap),
+        Removed: removed,
+        Error:   errors.Join(err, removeErr),
+    })
+}
+
+// pull writes configMap and returns its result. Atomic pulls replace the
+// whole output directory, so they write every watched ConfigMap again.
+func (w *watcher) pull(configMap *corev1.ConfigMap) (*PullConfigMapResult, error) {
+    run, _ := newPullRun(w.root, w.opts, w.defaultLayout) // options were validated up front
+    if !w.opts.Atomic {
+        run.addConfigMap(configMap)
+        results, err := run.finish(w.ctx) // only fails when interrupted
+        return &results[0], err
+    }
+
+    var configMaps []corev1.ConfigMap
+    for _, obj := range w.store.List() {
+        configMaps = append(configMaps, *obj.(*corev1.ConfigMap))
+    }
+    run.addConfigMaps(configMaps)
+    results, err := run.finish(w.ctx)
+    for i := range results {
+        if results[i].Namespace == configMap.Namespace && results[i].ConfigMapName == configMap.Name {
+            return &results[i], err
+        }
+    }
+    // deleted, or skipped because it is empty
+    return emptyResult(configMap), err
+}
+
+// emptyResult is the result for a ConfigMap without files
+func emptyResult(configMap *corev1.ConfigMap) *PullConfigMapResult {
+    return &PullConfigMapResult{
+        Kind:          KindConfigMap,
+        ConfigMapName: configMap.Name,
+        Namespace:     configMap.Namespace,
+        SavedFiles:    []SaveResult{},
+    }
+}
+
+// removeFiles deletes paths and frees them for other keys. Files that are
+// already gone count as removed; atomic pulls have dropped them already.
+func (w *watcher) removeFiles(paths []string) ([]string, error) {
+    removed := []string{}
+    var errs []error
+    for _, path := range paths {
+        rel, _ := filepath.Rel(w.root, path)
+        w.tracker.release(rel)
+        if w.opts.Atomic {
+            removed = append(removed, path)
+            continue
+        }
+        if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+            errs = append(errs, fmt.Errorf("failed to remove '%s': %w", path, err))
+            continue
+        }
+        removed = append(removed, path)
+    }
+    return removed, errors.Join(errs...)
+}
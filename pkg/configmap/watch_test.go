@@ -0,0 +1,40 @@
+package configmap
+
+import (
+    "context"
+    "sync"
+    "testing"
+
+    "k8s.io/apimachinery/pkg/runtime"
+    "k8s.io/client-go/kubernetes/fake"
+    k8stesting "k8s.io/client-go/testing"
+)
+
+func TestWatchConfigMapsFieldSelector(t *testing.T) {
+    clientset := fake.NewClientset(newConfigMap("default", "app", map[string]string{"a": "a"}, nil))
+    ctx, cancel := context.WithCancel(context.Background())
+    defer cancel()
+
+    var (
+        mu       sync.Mutex
+        selector string
+    )
+    clientset.PrependReactor("list", "configmaps", func(action k8stesting.Action) (bool, runtime.Object, error) {
+        mu.Lock()
+        defer mu.Unlock()
+        selector = action.(k8stesting.ListActionImpl).GetListOptions().FieldSelector
+        cancel()
+        return false, nil, nil
+    })
+
+    opts := PullOptions{ListOptions: ListOptions{FieldSelector: "metadata.namespace=default"}}
+    if err := NewOperations(clientset).WatchConfigMaps(ctx, "default", "app", t.TempDir(), opts, func(WatchEvent) {}); err != nil {
+        t.Fatalf("WatchConfigMaps failed: %v", err)
+    }
+
+    mu.Lock()
+    defer mu.Unlock()
+    if want := "metadata.namespace=default,metadata.name=app"; selector != want {
+        t.Errorf("field selector = %q, want %q", selector, want)
+    }
+}
@@ -0,0 +1,213 @@
+package diff
+
+import (
+    "fmt"
+    "strings"
+)
+
+// maxCells bounds the size of the LCS table; larger inputs are reported as a
+// single hunk replacing every differing line.
+const maxCells = 4_000_000
+
+type opKind int
+
+const (
+    opEqual opKind = iota
+    opDelete
+    opInsert
+)
+
+type op struct {
+    kind opKind
+    line string
+}
+
+// Unified returns a unified diff of a and b with the given number of context
+// lines, or an empty string when they are equal.
+func Unified(fromName, toName, a, b string, context int) string {
+    if a == b {
+        return ""
+    }
+
+    ops := lineOps(splitLines(a), splitLines(b))
+
+    var sb strings.Builder
+    fmt.Fprintf(&sb, "--- %s\n", fromName)
+    fmt.Fprintf(&sb, "+++ %s\n", toName)
+
+    // Walk the edit script, emitting a hunk for every run of changes padded
+    // with up to context lines of unchanged text on each side.
+    i := 0
+    for i < len(ops) {
+        if ops[i].kind == opEqual {
+            i++
+            continue
+        }
+
+        start := i - context
+        if start < 0 {
+            start = 0
+        }
+
+        end := i
+        for end < len(ops) {
+            if ops[end].kind != opEqual {
+                end++
+                continue
+            }
+            run := end
+            for run < len(ops) && ops[run].kind == opEqual {
+                run++
+            }
+            if run == len(ops) || run-end > 2*context {
+                end += min(context, run-end)
+                break
+            }
+            end = run
+        }
+
+        writeHunk(&sb, ops, start, end)
+        i = end
+    }
+
+    return sb.String()
+}
+
+// writeHunk writes ops[start:end] with its @@ header
+func writeHunk(sb *strings.Builder, ops []op, start, end int) {
+    fromLine, toLine := 1, 1
+    for _, o := range ops[:start] {
+        if o.kind != opInsert {
+            fromLine++
+        }
+        if o.kind != opDelete {
+            toLine++
+        }
+    }
+
+    fromCount, toCount := 0, 0
+    for _, o := range ops[start:end] {
+        if o.kind != opInsert {
+            fromCount++
+        }
+        if o.kind != opDelete {
+            toCount++
+        }
+    }
+    if fromCount == 0 {
+        fromLine--
+    }
+    if toCount == 0 {
+        toLine--
+    }
+
+    fmt.Fprintf(sb, "@@ -%s +%s @@\n", hunkRange(fromLine, fromCount), hunkRange(toLine, toCount))
+    for _, o := range ops[start:end] {
+        switch o.kind {
+        case opEqual:
+            sb.WriteString(" ")
+        case opDelete:
+            sb.WriteString("-")
+        case opInsert:
+            sb.WriteString("+")
+        }
+        sb.WriteString(o.line)
+        sb.WriteString("\n")
+    }
+}
+
+func hunkRange(line, count int) string {
+    if count == 1 {
+        return fmt.Sprintf("%d", line)
+    }
+    return fmt.Sprintf("%d,%d", line, count)
+}
+
+// splitLines splits s into lines, marking a missing trailing newline the way
+// diff(1) does
+func splitLines(s string) []string {
+    if s == "" {
+        return nil
+    }
+    lines := strings.Split(s, "\n")
+    if lines[len(lines)-1] == "" {
+        return lines[:len(lines)-1]
+    }
+    lines[len(lines)-1] += "\n\\ No newline at end of file"
+    return lines
+}
+
+// lineOps computes a minimal edit script turning a into b
+func lineOps(a, b []string) []op {
+    var ops []op
+
+    // Common prefix and suffix are cheap to strip and keep the table small
+    prefix := 0
+    for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+        prefix++
+    }
+    suffix := 0
+    for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+        suffix++
+    }
+
+    for _, line := range a[:prefix] {
+        ops = append(ops, op{opEqual, line})
+    }
+    ops = append(ops, lcsOps(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix])...)
+    for _, line := range a[len(a)-suffix:] {
+        ops = append(ops, op{opEqual, line})
+    }
+    return ops
+}
+
+func lcsOps(a, b []string) []op {
+    var ops []op
+    if len(a)*len(b) > maxCells {
+        for _, line := range a {
+            ops = append(ops, op{opDelete, line})
+        }
+        for _, line := range b {
+            ops = append(ops, op{opInsert, line})
+        }
+        return ops
+    }
+
+    // lengths[i][j] is the LCS length of a[i:] and b[j:]
+    lengths := make([][]int, len(a)+1)
+    for i := range lengths {
+        lengths[i] = make([]int, len(b)+1)
+    }
+    for i := len(a) - 1; i >= 0; i-- {
+        for j := len(b) - 1; j >= 0; j-- {
+            if a[i] == b[j] {
+                lengths[i][j] = lengths[i+1][j+1] + 1
+            } else {
+                lengths[i][j] = max(lengths[i+1][j], lengths[i][j+1])
+            }
+        }
+    }
+
+    i, j := 0, 0
+    for i < len(a) && j < len(b) {
+        switch {
+        case a[i] == b[j]:
+            ops = append(ops, op{opEqual, a[i]})
+            i++
+            j++
+        case lengths[i+1][j] >= lengths[i][j+1]:
+            ops = append(ops, op{opDelete, a[i]})
+            i++
+        default:
+            ops = append(ops, op{opInsert, b[j]})
+            j++
+        }
+    }
+    for ; i < len(a); i++ {
+        ops = append(ops, op{opDelete, a[i]})
+    }
+    for ; j < len(b); j++ {
+        ops = append(ops, op{opInsert, b[j]})
+    }
+    return ops
+}
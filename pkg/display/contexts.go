@@ -0,0 +1,68 @@
+package display
+
+import (
+    "bytes"
+    "fmt"
+)
+
+// contextView is the structured output of one kubeconfig context
+type contextView struct {
+    Context string        `json:"context"`
+    Error   string        `json:"error,omitempty"`
+    Results []interface{} `json:"results"`
+}
+
+// ForContext returns a printer with the same format that buffers the output
+// of one kubeconfig context. Several context printers can be used in
+// parallel; PrintContexts then writes their output grouped per cluster.
+func (p *Printer) ForContext(context string) *Printer {
+    return &Printer{
+        format:   p.format,
+        jsonPath: p.jsonPath,
+        out:      &bytes.Buffer{},
+        context:  context,
+    }
+}
+
+// PrintContexts writes the output collected by context printers, in order.
+// errs holds the error that ended each context's run, if any; it is part of
+// json and yaml documents and left to the caller otherwise.
+func (p *Printer) PrintContexts(printers []*Printer, errs []error) error {
+    switch p.format {
+    case FormatJSON, FormatYAML:
+        view := listView[contextView]{Items: []contextView{}}
+        for i, printer := range printers {
+            item := contextView{Context: printer.context, Results: nonNil(printer.objects)}
+            if errs[i] != nil {
+                item.Error = errs[i].Error()
+            }
+            view.Items = append(view.Items, item)
+        }
+        return p.printObject(view)
+    case FormatJSONPath:
+        // Templates are evaluated here, one context at a time, since a
+        // parsed jsonpath cannot be executed concurrently
+        for _, printer := range printers {
+            for _, obj := range printer.objects {
+                if err := p.printObject(obj); err != nil {
+                    return err
+                }
+            }
+        }
+        return nil
+    case FormatName:
+        for _, printer := range printers {
+            p.out.Write(printer.out.(*bytes.Buffer).Bytes())
+        }
+        return nil
+    }
+
+    for i, printer := range printers {
+        if i > 0 {
+            fmt.Fprintln(p.out)
+        }
+        fmt.Fprintf(p.out, "=== Context: %s ===\n", printer.context)
+        p.out.Write(printer.out.(*bytes.Buffer).Bytes())
+    }
+    return nil
+}
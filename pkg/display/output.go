@@ -2,107 +2,743 @@ package display
 
 import (
     "fmt"
+    "sort"
+    "strings"
+    "text/tabwriter"
+    "time"
+
+    "k8s.io/apimachinery/pkg/util/duration"
     "kmget/pkg/client"
     "kmget/pkg/configmap"
 )
 
-// PrintClusterInfo displays cluster information in a formatted way
-func PrintClusterInfo(info *client.ClusterInfo) {
-    fmt.Println("═══════════════════════════════════════════════════════════")
-    fmt.Printf("Connected to Kubernetes Cluster\n")
-    fmt.Println("═══════════════════════════════════════════════════════════")
-    fmt.Printf("  Context:    %s\n", info.Context)
-    fmt.Printf("  Cluster:    %s\n", info.Cluster)
-    fmt.Printf("  Endpoint:   %s\n", info.Endpoint)
-    fmt.Printf("  Namespace:  %s\n", info.Namespace)
-    fmt.Printf("  Version:    %s\n", info.Version)
-    fmt.Println("═══════════════════════════════════════════════════════════")
-    fmt.Println()
+// PrintClusterInfo displays cluster information in the printer's format
+func (p *Printer) PrintClusterInfo(info *client.ClusterInfo) error {
+    if p.structured() {
+        return p.printObject(info)
+    }
+    if p.format == FormatName {
+        fmt.Fprintln(p.out, info.Context)
+        return nil
+    }
+
+    fmt.Fprintln(p.out, "═══════════════════════════════════════════════════════════")
+    fmt.Fprintf(p.out, "Connected to Kubernetes Cluster\n")
+    fmt.Fprintln(p.out, "═══════════════════════════════════════════════════════════")
+    fmt.Fprintf(p.out, "  Context:    %s\n", info.Context)
+    fmt.Fprintf(p.out, "  Cluster:    %s\n", info.Cluster)
+    fmt.Fprintf(p.out, "  Endpoint:   %s\n", info.Endpoint)
+    fmt.Fprintf(p.out, "  Namespace:  %s (from %s)\n", info.Namespace, info.NamespaceSource)
+    fmt.Fprintf(p.out, "  Version:    %s\n", info.Version)
+    if p.format == FormatWide {
+        fmt.Fprintf(p.out, "  Kubeconfig: %s\n", info.KubeconfigPath)
+    }
+    fmt.Fprintln(p.out, "═══════════════════════════════════════════════════════════")
+    fmt.Fprintln(p.out)
+    return nil
 }
 
 // PrintConfigMapsList displays a list of ConfigMaps
-func PrintConfigMapsList(namespace string, configMaps []configmap.ConfigMapInfo) {
-    fmt.Printf("ConfigMaps in namespace '%s':\n", namespace)
+func (p *Printer) PrintConfigMapsList(namespace string, configMaps []configmap.ConfigMapInfo) error {
+    switch {
+    case p.structured():
+        return p.printObject(listView[configmap.ConfigMapInfo]{Items: nonNil(configMaps)})
+    case p.format == FormatName:
+        p.printConfigMapNames(configMaps)
+    case p.format == FormatWide:
+        p.printConfigMapsWide(configMaps)
+    default:
+        fmt.Fprintf(p.out, "ConfigMaps in namespace '%s':\n", namespace)
+        p.printConfigMaps(configMaps)
+    }
+    return nil
+}
+
+// PrintAllConfigMapsList displays ConfigMaps from all namespaces
+func (p *Printer) PrintAllConfigMapsList(allConfigMaps map[string][]configmap.ConfigMapInfo) error {
+    namespaces := sortedKeys(allConfigMaps)
+
+    var flattened []configmap.ConfigMapInfo
+    for _, namespace := range namespaces {
+        flattened = append(flattened, allConfigMaps[namespace]...)
+    }
+
+    switch {
+    case p.structured():
+        return p.printObject(listView[configmap.ConfigMapInfo]{Items: nonNil(flattened)})
+    case p.format == FormatName:
+        p.printConfigMapNames(flattened)
+    case p.format == FormatWide:
+        p.printConfigMapsWide(flattened)
+    default:
+        fmt.Fprintln(p.out, "ConfigMaps across all namespaces:")
+        for _, namespace := range namespaces {
+            configMaps := allConfigMaps[namespace]
+            if len(configMaps) > 0 {
+                fmt.Fprintf(p.out, "\nNamespace: %s\n", namespace)
+                p.printConfigMaps(configMaps)
+            }
+        }
+    }
+    return nil
+}
+
+func (p *Printer) printConfigMaps(configMaps []configmap.ConfigMapInfo) {
     for _, cm := range configMaps {
-        fmt.Printf("  - %s (data: %d, binary: %d)\n", cm.Name, cm.DataCount, cm.BinaryCount)
+        fmt.Fprintf(p.out, "  - %s (data: %d, binary: %d)\n", cm.Name, cm.DataCount, cm.BinaryCount)
         for _, key := range cm.DataKeys {
-            fmt.Printf("    * %s (text)\n", key)
+            fmt.Fprintf(p.out, "    * %s (text)\n", key)
         }
         for _, key := range cm.BinaryKeys {
-            fmt.Printf("    * %s (binary)\n", key)
+            fmt.Fprintf(p.out, "    * %s (binary)\n", key)
         }
     }
 }
 
-// PrintAllConfigMapsList displays ConfigMaps from all namespaces
-func PrintAllConfigMapsList(allConfigMaps map[string][]configmap.ConfigMapInfo) {
-    fmt.Println("ConfigMaps across all namespaces:")
-    for namespace, configMaps := range allConfigMaps {
-        if len(configMaps) > 0 {
-            fmt.Printf("\nNamespace: %s\n", namespace)
-            for _, cm := range configMaps {
-                fmt.Printf("  - %s (data: %d, binary: %d)\n", cm.Name, cm.DataCount, cm.BinaryCount)
-                for _, key := range cm.DataKeys {
-                    fmt.Printf("    * %s (text)\n", key)
-                }
-                for _, key := range cm.BinaryKeys {
-                    fmt.Printf("    * %s (binary)\n", key)
-                }
+func (p *Printer) printConfigMapNames(configMaps []configmap.ConfigMapInfo) {
+    for _, cm := range configMaps {
+        fmt.Fprintf(p.out, "configmap/%s\n", cm.Name)
+    }
+}
+
+func (p *Printer) printConfigMapsWide(configMaps []configmap.ConfigMapInfo) {
+    w := tabwriter.NewWriter(p.out, 0, 0, 3, ' ', 0)
+    fmt.Fprintln(w, "NAMESPACE\tNAME\tDATA\tBINARY\tKEYS\tCONSUMERS")
+    for _, cm := range configMaps {
+        keys := append(append([]string{}, cm.DataKeys...), cm.BinaryKeys...)
+        consumers := "<unknown>"
+        switch {
+        case cm.Consumers == nil:
+        case len(cm.Consumers) == 0:
+            consumers = "<none>"
+        default:
+            consumers = strings.Join(cm.Consumers, ",")
+        }
+        fmt.Fprintf(w, "%s\t%s\t%d\t%d\t%s\t%s\n", cm.Namespace, cm.Name, cm.DataCount, cm.BinaryCount, strings.Join(keys, ","), consumers)
+    }
+    w.Flush()
+}
+
+// PrintSecretsList displays a list of Secrets, hiding value sizes when mask is set
+func (p *Printer) PrintSecretsList(namespace string, secrets []configmap.SecretInfo, mask bool) error {
+    switch {
+    case p.structured():
+        return p.printObject(listView[configmap.SecretInfo]{Items: maskSecrets(secrets, mask)})
+    case p.format == FormatName:
+        p.printSecretNames(secrets)
+    case p.format == FormatWide:
+        p.printSecretsWide(secrets, mask)
+    default:
+        fmt.Fprintf(p.out, "Secrets in namespace '%s':\n", namespace)
+        p.printSecrets(secrets, mask)
+    }
+    return nil
+}
+
+// PrintAllSecretsList displays Secrets from all namespaces
+func (p *Printer) PrintAllSecretsList(allSecrets map[string][]configmap.SecretInfo, mask bool) error {
+    namespaces := sortedKeys(allSecrets)
+
+    var flattened []configmap.SecretInfo
+    for _, namespace := range namespaces {
+        flattened = append(flattened, allSecrets[namespace]...)
+    }
+
+    switch {
+    case p.structured():
+        return p.printObject(listView[configmap.SecretInfo]{Items: maskSecrets(flattened, mask)})
+    case p.format == FormatName:
+        p.printSecretNames(flattened)
+    case p.format == FormatWide:
+        p.printSecretsWide(flattened, mask)
+    default:
+        fmt.Fprintln(p.out, "Secrets across all namespaces:")
+        for _, namespace := range namespaces {
+            secrets := allSecrets[namespace]
+            if len(secrets) > 0 {
+                fmt.Fprintf(p.out, "\nNamespace: %s\n", namespace)
+                p.printSecrets(secrets, mask)
             }
         }
     }
+    return nil
+}
+
+func (p *Printer) printSecrets(secrets []configmap.SecretInfo, mask bool) {
+    for _, secret := range secrets {
+        fmt.Fprintf(p.out, "  - %s (type: %s, keys: %d)\n", secret.Name, secret.Type, secret.KeyCount)
+        for _, key := range secret.Keys {
+            if mask {
+                fmt.Fprintf(p.out, "    * %s (****)\n", key)
+            } else {
+                fmt.Fprintf(p.out, "    * %s (%d bytes)\n", key, secret.KeySizes[key])
+            }
+        }
+    }
+}
+
+func (p *Printer) printSecretNames(secrets []configmap.SecretInfo) {
+    for _, secret := range secrets {
+        fmt.Fprintf(p.out, "secret/%s\n", secret.Name)
+    }
+}
+
+func (p *Printer) printSecretsWide(secrets []configmap.SecretInfo, mask bool) {
+    w := tabwriter.NewWriter(p.out, 0, 0, 3, ' ', 0)
+    fmt.Fprintln(w, "NAMESPACE\tNAME\tTYPE\tKEYS")
+    for _, secret := range secrets {
+        keys := make([]string, 0, len(secret.Keys))
+        for _, key := range secret.Keys {
+            if mask {
+                keys = append(keys, key)
+            } else {
+                keys = append(keys, fmt.Sprintf("%s(%d)", key, secret.KeySizes[key]))
+            }
+        }
+        fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", secret.Namespace, secret.Name, secret.Type, strings.Join(keys, ","))
+    }
+    w.Flush()
+}
+
+// maskSecrets drops value sizes from structured output when mask is set
+func maskSecrets(secrets []configmap.SecretInfo, mask bool) []configmap.SecretInfo {
+    masked := make([]configmap.SecretInfo, 0, len(secrets))
+    for _, secret := range secrets {
+        if mask {
+            secret.KeySizes = nil
+        }
+        masked = append(masked, secret)
+    }
+    return masked
 }
 
 // PrintPullResult displays the result of pulling a ConfigMap
-func PrintPullResult(result *configmap.PullConfigMapResult) {
-    fmt.Printf("Pulling ConfigMap '%s' from namespace '%s':\n", result.ConfigMapName, result.Namespace)
-    
+func (p *Printer) PrintPullResult(result *configmap.PullConfigMapResult) error {
+    if p.structured() {
+        return p.printObject(result)
+    }
+    if p.format == FormatName {
+        p.printSavedPaths(result.SavedFiles)
+        return nil
+    }
+
+    fmt.Fprintf(p.out, "Pulling %s '%s' from namespace '%s':\n", result.Kind, result.ConfigMapName, result.Namespace)
+
+    successCount := p.printSavedFiles(result.SavedFiles)
+
+    fmt.Fprintf(p.out, "\nSuccessfully pulled %d/%d configuration file(s)\n", successCount, result.TotalFiles)
+    return nil
+}
+
+// PrintPullAllResults displays the results of pulling all ConfigMaps
+func (p *Printer) PrintPullAllResults(all *configmap.PullAllResult) error {
+    if p.structured() {
+        return p.printObject(configmap.PullAllResult{
+            Results:  nonNil(all.Results),
+            Failures: nonNil(all.Failures),
+        })
+    }
+    results := all.Results
+    if p.format == FormatName {
+        for _, result := range results {
+            p.printSavedPaths(result.SavedFiles)
+        }
+        return nil
+    }
+
+    totalFiles := 0
+    successfulFiles := 0
+
+    fmt.Fprintf(p.out, "Found %s to process\n", describeCounts(results))
+    fmt.Fprintln(p.out, "Pulling from all namespaces:")
+
+    for _, result := range results {
+        fmt.Fprintf(p.out, "\n[Namespace: %s] %s: %s (%d files)\n",
+            result.Namespace, result.Kind, result.ConfigMapName, result.TotalFiles)
+
+        totalFiles += len(result.SavedFiles)
+        successfulFiles += p.printSavedFiles(result.SavedFiles)
+    }
+
+    fmt.Fprintf(p.out, "\nSummary:\n")
+    fmt.Fprintf(p.out, "  - Processed %s\n", describeCounts(results))
+    fmt.Fprintf(p.out, "  - Successfully saved %d/%d configuration file(s)\n", successfulFiles, totalFiles)
+    if len(all.Failures) > 0 {
+        fmt.Fprintf(p.out, "  - %d failure(s):\n", len(all.Failures))
+        for _, failure := range all.Failures {
+            if failure.Name == "" {
+                fmt.Fprintf(p.out, "    ✗ [Namespace: %s] %v\n", failure.Namespace, failure.Error)
+            } else {
+                fmt.Fprintf(p.out, "    ✗ [Namespace: %s] %s: %s: %v\n", failure.Namespace, failure.Kind, failure.Name, failure.Error)
+            }
+        }
+    }
+    return nil
+}
+
+// PrintPodPullResult displays the files and environment pulled for a workload
+func (p *Printer) PrintPodPullResult(result *configmap.PodPullResult) error {
+    if p.structured() {
+        return p.printObject(configmap.PodPullResult{
+            Workload:  result.Workload,
+            Namespace: result.Namespace,
+            Container: result.Container,
+            Results:   nonNil(result.Results),
+            EnvFile:   result.EnvFile,
+            Skipped:   nonNil(result.Skipped),
+        })
+    }
+    if p.format == FormatName {
+        for _, pulled := range result.Results {
+            p.printSavedPaths(pulled.SavedFiles)
+        }
+        if result.EnvFile != nil {
+            p.printSavedPaths([]configmap.SaveResult{*result.EnvFile})
+        }
+        return nil
+    }
+
+    fmt.Fprintf(p.out, "Pulling configuration of container '%s' of %s in namespace '%s':\n", result.Container, result.Workload, result.Namespace)
+
+    totalFiles, successCount := 0, 0
+    for _, pulled := range result.Results {
+        fmt.Fprintf(p.out, "\n%s: %s (%d files)\n", pulled.Kind, pulled.ConfigMapName, pulled.TotalFiles)
+        successCount += p.printSavedFiles(pulled.SavedFiles)
+        totalFiles += pulled.TotalFiles
+    }
+    if result.EnvFile != nil {
+        fmt.Fprintln(p.out, "\nEnvironment:")
+        successCount += p.printSavedFiles([]configmap.SaveResult{*result.EnvFile})
+        totalFiles++
+    }
+    for _, ref := range result.Skipped {
+        if ref.Key != "" {
+            fmt.Fprintf(p.out, "  - Skipped optional key '%s' of ConfigMap '%s'\n", ref.Key, ref.Name)
+        } else {
+            fmt.Fprintf(p.out, "  - Skipped optional ConfigMap '%s' (%s)\n", ref.Name, ref.Type)
+        }
+    }
+
+    fmt.Fprintf(p.out, "\nSuccessfully pulled %d/%d configuration file(s)\n", successCount, totalFiles)
+    return nil
+}
+
+// PrintUsageResult displays the consumers of a ConfigMap and of each key
+func (p *Printer) PrintUsageResult(result *configmap.UsageResult) error {
+    if p.structured() {
+        return p.printObject(result)
+    }
+    if p.format == FormatName {
+        for _, consumer := range result.Consumers {
+            fmt.Fprintln(p.out, strings.ToLower(consumer.Kind)+"/"+consumer.Name)
+        }
+        return nil
+    }
+
+    if result.Missing {
+        fmt.Fprintf(p.out, "ConfigMap '%s' does not exist in namespace '%s'\n", result.ConfigMapName, result.Namespace)
+    }
+    if len(result.Consumers) == 0 {
+        fmt.Fprintf(p.out, "ConfigMap '%s' in namespace '%s' is not used by any workload\n", result.ConfigMapName, result.Namespace)
+    } else {
+        fmt.Fprintf(p.out, "ConfigMap '%s' in namespace '%s' is used by %d workload(s):\n", result.ConfigMapName, result.Namespace, len(result.Consumers))
+        for _, consumer := range result.Consumers {
+            fmt.Fprintf(p.out, "  %s\n", consumer)
+            for _, ref := range consumer.References {
+                fmt.Fprintf(p.out, "    - %s\n", describeReference(ref))
+            }
+        }
+    }
+    if len(result.Keys) == 0 {
+        return nil
+    }
+
+    fmt.Fprintln(p.out, "\nKeys:")
+    w := tabwriter.NewWriter(p.out, 0, 0, 3, ' ', 0)
+    for _, key := range result.Keys {
+        consumers := strings.Join(key.Consumers, ", ")
+        if consumers == "" {
+            consumers = "<unused>"
+        }
+        if !key.Exists {
+            consumers += " (missing)"
+        }
+        fmt.Fprintf(w, "  %s\t%s\n", key.Key, consumers)
+    }
+    return w.Flush()
+}
+
+// PrintUnusedConfigMaps displays ConfigMaps and keys no workload reads
+func (p *Printer) PrintUnusedConfigMaps(unused []configmap.UnusedConfigMap) error {
+    if p.structured() {
+        return p.printObject(listView[configmap.UnusedConfigMap]{Items: nonNil(unused)})
+    }
+    if p.format == FormatName {
+        for _, cm := range unused {
+            if cm.Unused {
+                fmt.Fprintf(p.out, "configmap/%s\n", cm.Name)
+            }
+        }
+        return nil
+    }
+    if len(unused) == 0 {
+        fmt.Fprintln(p.out, "No unused ConfigMaps or keys found")
+        return nil
+    }
+
+    w := tabwriter.NewWriter(p.out, 0, 0, 3, ' ', 0)
+    fmt.Fprintln(w, "NAMESPACE\tNAME\tAGE\tSTATUS\tUNUSED KEYS")
+    for _, cm := range unused {
+        status := "unused"
+        if !cm.Unused {
+            status = "partly used by " + strings.Join(cm.Consumers, ",")
+        }
+        age := duration.HumanDuration(time.Since(cm.CreationTimestamp))
+        fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", cm.Namespace, cm.Name, age, status, strings.Join(cm.UnusedKeys, ","))
+    }
+    return w.Flush()
+}
+
+// describeReference renders a ConfigMap reference for humans
+func describeReference(ref configmap.ConfigMapReference) string {
+    var description string
+    switch ref.Type {
+    case configmap.RefEnvFrom:
+        description = fmt.Sprintf("envFrom in container '%s'", ref.Container)
+        if ref.Prefix != "" {
+            description += fmt.Sprintf(" with prefix '%s'", ref.Prefix)
+        }
+    case configmap.RefEnv:
+        description = fmt.Sprintf("env %s from key '%s' in container '%s'", ref.EnvName, ref.Key, ref.Container)
+    default:
+        description = fmt.Sprintf("volume '%s'", ref.Volume)
+        if ref.Type == configmap.RefProjected {
+            description = "projected " + description
+        }
+        if len(ref.Items) > 0 {
+            keys := make([]string, 0, len(ref.Items))
+            for _, item := range ref.Items {
+                keys = append(keys, item.Key)
+            }
+            description += fmt.Sprintf(" (keys: %s)", strings.Join(keys, ", "))
+        }
+    }
+    if ref.Optional {
+        description += ", optional"
+    }
+    return description
+}
+
+// PrintWatchEvent displays one sync of a watched ConfigMap
+func (p *Printer) PrintWatchEvent(event *configmap.WatchEvent) error {
+    if p.structured() {
+        return p.printObject(event)
+    }
+    if p.format == FormatName {
+        p.printSavedPaths(event.Result.SavedFiles)
+        return nil
+    }
+
+    result := event.Result
+    if event.Type == configmap.WatchDeleted {
+        fmt.Fprintf(p.out, "%s '%s' deleted from namespace '%s':\n", result.Kind, result.ConfigMapName, result.Namespace)
+    } else {
+        fmt.Fprintf(p.out, "Syncing %s '%s' from namespace '%s':\n", result.Kind, result.ConfigMapName, result.Namespace)
+    }
+    successCount := p.printSavedFiles(result.SavedFiles)
+    for _, path := range event.Removed {
+        fmt.Fprintf(p.out, "  - Removed: %s\n", path)
+    }
+    if event.Error != nil {
+        fmt.Fprintf(p.out, "  ✗ %v\n", event.Error)
+    }
+
+    if event.Type == configmap.WatchSynced {
+        fmt.Fprintf(p.out, "\nSynced %d/%d configuration file(s), removed %d\n", successCount, result.TotalFiles, len(event.Removed))
+    }
+    return nil
+}
+
+// printSavedFiles lists saved files and returns how many were written
+func (p *Printer) printSavedFiles(files []configmap.SaveResult) int {
     successCount := 0
-    for _, file := range result.SavedFiles {
+    for _, file := range files {
         if file.Success {
             if file.Binary {
-                fmt.Printf("  ✓ Saved (binary): %s\n", file.Path)
+                fmt.Fprintf(p.out, "  ✓ Saved (binary): %s\n", file.Path)
             } else {
-                fmt.Printf("  ✓ Saved: %s\n", file.Path)
+                fmt.Fprintf(p.out, "  ✓ Saved: %s\n", file.Path)
             }
             successCount++
         } else {
-            fmt.Printf("  ✗ Failed to save: %s (error: %v)\n", file.Path, file.Error)
+            fmt.Fprintf(p.out, "  ✗ Failed to save: %s (error: %v)\n", file.Path, file.Error)
         }
     }
-    
-    fmt.Printf("\nSuccessfully pulled %d/%d configuration file(s)\n", successCount, result.TotalFiles)
+    return successCount
 }
 
-// PrintPullAllResults displays the results of pulling all ConfigMaps
-func PrintPullAllResults(results []configmap.PullConfigMapResult) {
-    totalConfigMaps := len(results)
-    totalFiles := 0
-    successfulFiles := 0
+func (p *Printer) printSavedPaths(files []configmap.SaveResult) {
+    for _, file := range files {
+        if file.Success {
+            fmt.Fprintln(p.out, file.Path)
+        }
+    }
+}
 
-    fmt.Printf("Found %d ConfigMap(s) to process\n", totalConfigMaps)
-    fmt.Println("Pulling ConfigMaps from all namespaces:")
+// describeCounts summarizes pull results per kind, e.g. "3 ConfigMap(s), 1 Secret(s)"
+func describeCounts(results []configmap.PullConfigMapResult) string {
+    configMaps, secrets := 0, 0
+    for _, result := range results {
+        if result.Kind == configmap.KindSecret {
+            secrets++
+        } else {
+            configMaps++
+        }
+    }
+
+    if secrets == 0 {
+        return fmt.Sprintf("%d ConfigMap(s)", configMaps)
+    }
+    if configMaps == 0 {
+        return fmt.Sprintf("%d Secret(s)", secrets)
+    }
+    return fmt.Sprintf("%d ConfigMap(s), %d Secret(s)", configMaps, secrets)
+}
 
+// PrintPushResult displays the result of pushing a directory to a ConfigMap
+func (p *Printer) PrintPushResult(result *configmap.PushConfigMapResult) error {
+    if p.structured() {
+        return p.printObject(result)
+    }
+    if p.format == FormatName {
+        fmt.Fprintf(p.out, "configmap/%s\n", result.ConfigMapName)
+        return nil
+    }
+
+    verb := "Updating"
+    if result.Created {
+        verb = "Creating"
+    }
+    fmt.Fprintf(p.out, "%s ConfigMap '%s' in namespace '%s':\n", verb, result.ConfigMapName, result.Namespace)
+
+    successCount := 0
+    for _, file := range result.PushedFiles {
+        if file.Success {
+            if file.Binary {
+                fmt.Fprintf(p.out, "  ✓ %s (binary): %s\n", file.Action, file.Key)
+            } else {
+                fmt.Fprintf(p.out, "  ✓ %s: %s\n", file.Action, file.Key)
+            }
+            successCount++
+        } else if file.Error != nil {
+            fmt.Fprintf(p.out, "  ✗ Failed to read: %s (error: %v)\n", file.Path, file.Error)
+        } else {
+            fmt.Fprintf(p.out, "  - Not pushed: %s\n", file.Key)
+        }
+    }
+    for _, key := range result.RemovedKeys {
+        fmt.Fprintf(p.out, "  ✓ %s: %s\n", configmap.KeyRemoved, key)
+    }
+
+    fmt.Fprintf(p.out, "\nSuccessfully pushed %d/%d configuration file(s)\n", successCount, result.TotalFiles)
+    return nil
+}
+
+// PrintDiffResults displays drift between ConfigMaps and local files
+func (p *Printer) PrintDiffResults(results []configmap.DiffResult) error {
+    if p.structured() {
+        return p.printObject(listView[configmap.DiffResult]{Items: nonNil(results)})
+    }
+    if p.format == FormatName {
+        for _, result := range results {
+            for _, change := range result.Changes {
+                fmt.Fprintln(p.out, change.Path)
+            }
+        }
+        return nil
+    }
+
+    drifted := 0
+    counts := map[string]int{}
     for _, result := range results {
-        fmt.Printf("\n[Namespace: %s] ConfigMap: %s (%d files)\n", 
-            result.Namespace, result.ConfigMapName, result.TotalFiles)
-        
-        for _, file := range result.SavedFiles {
-            totalFiles++
-            if file.Success {
-                if file.Binary {
-                    fmt.Printf("  ✓ Saved (binary): %s\n", file.Path)
-                } else {
-                    fmt.Printf("  ✓ Saved: %s\n", file.Path)
+        if !result.HasDrift() {
+            continue
+        }
+        drifted++
+
+        switch {
+        case result.ConfigMapName == "":
+            fmt.Fprintf(p.out, "Untracked files in %s:\n", result.Dir)
+        case result.Missing:
+            fmt.Fprintf(p.out, "ConfigMap '%s' in namespace '%s' does not exist (local: %s):\n", result.ConfigMapName, result.Namespace, result.Dir)
+        default:
+            fmt.Fprintf(p.out, "ConfigMap '%s' in namespace '%s' differs from %s:\n", result.ConfigMapName, result.Namespace, result.Dir)
+        }
+
+        for _, change := range result.Changes {
+            counts[change.Status]++
+            marker := "~"
+            switch change.Status {
+            case configmap.KeyAdded:
+                marker = "+"
+            case configmap.KeyRemoved:
+                marker = "-"
+            }
+
+            if change.Binary {
+                fmt.Fprintf(p.out, "  %s %s (binary): %s\n", marker, change.Status, change.Key)
+                if change.LiveHash != "" {
+                    fmt.Fprintf(p.out, "      live:  sha256:%s\n", change.LiveHash)
                 }
-                successfulFiles++
-            } else {
-                fmt.Printf("  ✗ Failed to save: %s (error: %v)\n", file.Path, file.Error)
+                if change.LocalHash != "" {
+                    fmt.Fprintf(p.out, "      local: sha256:%s\n", change.LocalHash)
+                }
+                continue
+            }
+
+            fmt.Fprintf(p.out, "  %s %s: %s\n", marker, change.Status, change.Key)
+            for _, line := range strings.Split(strings.TrimSuffix(change.Diff, "\n"), "\n") {
+                fmt.Fprintf(p.out, "      %s\n", line)
             }
         }
+        fmt.Fprintln(p.out)
     }
 
-    fmt.Printf("\nSummary:\n")
-    fmt.Printf("  - Processed %d ConfigMap(s)\n", totalConfigMaps)
-    fmt.Printf("  - Successfully saved %d/%d configuration file(s)\n", successfulFiles, totalFiles)
-}
\ No newline at end of file
+    if drifted == 0 {
+        fmt.Fprintln(p.out, "No drift detected")
+        return nil
+    }
+    fmt.Fprintf(p.out, "Drift detected in %d location(s): %d added, %d removed, %d changed\n",
+        drifted, counts[configmap.KeyAdded], counts[configmap.KeyRemoved], counts[configmap.KeyChanged])
+    return nil
+}
+
+// PrintCompareResults displays the comparison of ConfigMaps between two sources
+func (p *Printer) PrintCompareResults(results []configmap.CompareResult) error {
+    if p.structured() {
+        return p.printObject(listView[configmap.CompareResult]{Items: nonNil(results)})
+    }
+    if p.format == FormatName {
+        for _, result := range results {
+            if result.HasDifferences() {
+                fmt.Fprintf(p.out, "configmap/%s\n", result.ConfigMapName)
+            }
+        }
+        return nil
+    }
+
+    differing := 0
+    counts := map[string]int{}
+    for _, result := range results {
+        if result.HasDifferences() {
+            differing++
+        }
+
+        fmt.Fprintf(p.out, "ConfigMap '%s': %s ↔ %s\n", result.ConfigMapName, result.Left, result.Right)
+        switch {
+        case result.MissingLeft && result.MissingRight:
+            fmt.Fprintln(p.out, "  ConfigMap does not exist on either side")
+        case result.MissingLeft:
+            fmt.Fprintf(p.out, "  ConfigMap does not exist in %s\n", result.Left)
+        case result.MissingRight:
+            fmt.Fprintf(p.out, "  ConfigMap does not exist in %s\n", result.Right)
+        }
+
+        for _, key := range result.Keys {
+            counts[key.Status]++
+            switch key.Status {
+            case configmap.KeyIdentical:
+                fmt.Fprintf(p.out, "  = identical: %s\n", key.Key)
+                continue
+            case configmap.KeyMissingLeft:
+                fmt.Fprintf(p.out, "  > only in %s: %s\n", result.Right, key.Key)
+            case configmap.KeyMissingRight:
+                fmt.Fprintf(p.out, "  < only in %s: %s\n", result.Left, key.Key)
+            default:
+                fmt.Fprintf(p.out, "  ~ changed: %s\n", key.Key)
+            }
+
+            if key.Binary {
+                if key.LeftHash != "" {
+                    fmt.Fprintf(p.out, "      left:  sha256:%s\n", key.LeftHash)
+                }
+                if key.RightHash != "" {
+                    fmt.Fprintf(p.out, "      right: sha256:%s\n", key.RightHash)
+                }
+                continue
+            }
+            for _, line := range strings.Split(strings.TrimSuffix(key.Diff, "\n"), "\n") {
+                fmt.Fprintf(p.out, "      %s\n", line)
+            }
+        }
+        fmt.Fprintln(p.out)
+    }
+
+    fmt.Fprintf(p.out, "%d of %d ConfigMap(s) differ: %d identical, %d changed, %d only left, %d only right key(s)\n",
+        differing, len(results), counts[configmap.KeyIdentical], counts[configmap.KeyChanged],
+        counts[configmap.KeyMissingRight], counts[configmap.KeyMissingLeft])
+    return nil
+}
+
+// sortedKeys returns the keys of a namespace-indexed map in order
+func sortedKeys[T any](m map[string]T) []string {
+    keys := make([]string, 0, len(m))
+    for key := range m {
+        keys = append(keys, key)
+    }
+    sort.Strings(keys)
+    return keys
+}
+
+// nonNil makes empty lists encode as [] rather than null
+func nonNil[T any](items []T) []T {
+    if items == nil {
+        return []T{}
+    }
+    return items
+}
+
+// PrintRestoreResults displays the results of restoring a backup tree
+func (p *Printer) PrintRestoreResults(results []configmap.RestoreResult) error {
+    if p.structured() {
+        return p.printObject(listView[configmap.RestoreResult]{Items: nonNil(results)})
+    }
+    if p.format == FormatName {
+        for _, result := range results {
+            if result.Error == nil {
+                fmt.Fprintf(p.out, "configmap/%s\n", result.ConfigMapName)
+            }
+        }
+        return nil
+    }
+
+    counts := map[string]int{}
+    failed := 0
+    for _, result := range results {
+        target := result.Namespace
+        if result.SourceNamespace != result.Namespace {
+            target = fmt.Sprintf("%s (from %s)", result.Namespace, result.SourceNamespace)
+        }
+
+        if result.Error != nil {
+            fmt.Fprintf(p.out, "  ✗ %s/%s: %v\n", target, result.ConfigMapName, result.Error)
+            failed++
+            continue
+        }
+
+        action := result.Action
+        if result.DryRun {
+            action += " (dry run)"
+        }
+        fmt.Fprintf(p.out, "  ✓ %s/%s: %s (%d keys)\n", target, result.ConfigMapName, action, result.TotalKeys)
+        counts[result.Action]++
+    }
+
+    fmt.Fprintf(p.out, "\nSummary:\n")
+    fmt.Fprintf(p.out, "  - Created %d, overwritten %d, skipped %d ConfigMap(s)\n",
+        counts[configmap.RestoreCreated], counts[configmap.RestoreOverwritten], counts[configmap.RestoreSkipped])
+    if failed > 0 {
+        fmt.Fprintf(p.out, "  - Failed to restore %d ConfigMap(s)\n", failed)
+    }
+    return nil
+}
@@ -0,0 +1,123 @@
+package display
+
+import (
+    "encoding/json"
+    "fmt"
+    "io"
+    "strings"
+
+    "k8s.io/client-go/util/jsonpath"
+    "sigs.k8s.io/yaml"
+)
+
+// Output formats understood by NewPrinter
+const (
+    FormatTable    = "table"
+    FormatWide     = "wide"
+    FormatJSON     = "json"
+    FormatYAML     = "yaml"
+    FormatName     = "name"
+    FormatJSONPath = "jsonpath"
+)
+
+// Printer renders kmget results either as human readable text (table, wide),
+// as object names (name), or as structured documents (json, yaml, jsonpath).
+// Structured documents use the JSON field names of the result types.
+type Printer struct {
+    format   string
+    jsonPath *jsonpath.JSONPath
+    out      io.Writer
+    printed  int
+
+    // context is set on printers created by ForContext, which collect
+    // documents in objects instead of writing them
+    context string
+    objects []interface{}
+}
+
+// listView wraps a slice so list documents share the same shape
+type listView[T any] struct {
+    Items []T `json:"items"`
+}
+
+// NewPrinter creates a printer for format, which is one of json, yaml, table,
+// wide, name or jsonpath=TEMPLATE. An empty format means table.
+func NewPrinter(format string, out io.Writer) (*Printer, error) {
+    p := &Printer{format: format, out: out}
+
+    switch {
+    case format == "":
+        p.format = FormatTable
+    case format == FormatTable, format == FormatWide, format == FormatJSON, format == FormatYAML, format == FormatName:
+    case strings.HasPrefix(format, FormatJSONPath+"="):
+        template := strings.TrimPrefix(format, FormatJSONPath+"=")
+        p.format = FormatJSONPath
+        p.jsonPath = jsonpath.New("kmget").AllowMissingKeys(true)
+        if err := p.jsonPath.Parse(template); err != nil {
+            return nil, fmt.Errorf("invalid jsonpath template %q: %w", template, err)
+        }
+    default:
+        return nil, fmt.Errorf("invalid format %q (must be one of: json, yaml, table, wide, name, jsonpath=TEMPLATE)", format)
+    }
+
+    return p, nil
+}
+
+// structured reports whether the printer emits documents rather than text
+func (p *Printer) structured() bool {
+    return p.format == FormatJSON || p.format == FormatYAML || p.format == FormatJSONPath
+}
+
+// printObject writes obj as a JSON, YAML or jsonpath document. Successive
+// YAML documents are separated with "---" so the output stays parseable.
+func (p *Printer) printObject(obj interface{}) error {
+    defer func() { p.printed++ }()
+
+    if p.context != "" {
+        p.objects = append(p.objects, obj)
+        return nil
+    }
+
+    switch p.format {
+    case FormatJSON:
+        data, err := json.MarshalIndent(obj, "", "  ")
+        if err != nil {
+            return fmt.Errorf("failed to encode JSON: %w", err)
+        }
+        _, err = fmt.Fprintln(p.out, string(data))
+        return err
+    case FormatYAML:
+        data, err := yaml.Marshal(obj)
+        if err != nil {
+            return fmt.Errorf("failed to encode YAML: %w", err)
+        }
+        if p.printed > 0 {
+            fmt.Fprintln(p.out, "---")
+        }
+        _, err = p.out.Write(data)
+        return err
+    default:
+        // jsonpath evaluates against the generic JSON form of obj so that
+        // templates use the same field names as the json output
+        data, err := json.Marshal(obj)
+        if err != nil {
+            return fmt.Errorf("failed to encode JSON: %w", err)
+        }
+        var generic interface{}
+        if err := json.Unmarshal(data, &generic); err != nil {
+            return fmt.Errorf("failed to decode JSON: %w", err)
+        }
+        if err := p.jsonPath.Execute(p.out, generic); err != nil {
+            return fmt.Errorf("failed to execute jsonpath: %w", err)
+        }
+        _, err = fmt.Fprintln(p.out)
+        return err
+    }
+}
+
+// Separator writes a blank line between consecutive human readable sections
+func (p *Printer) Separator() {
+    if p.format == FormatTable || p.format == FormatWide {
+        fmt.Fprintln(p.out)
+    }
+}
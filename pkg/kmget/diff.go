@@ -0,0 +1,44 @@
+package kmget
+
+import "context"
+
+// DiffOptions selects what Diff compares
+type DiffOptions struct {
+    // Name of the ConfigMap to compare. Ignored with AllNamespaces.
+    Name string
+
+    // Namespace of the ConfigMap, the Client's namespace when empty
+    Namespace     string
+    AllNamespaces bool
+
+    // Dir is the directory a pull wrote to, the working directory when empty
+    Dir string
+
+    // Layout is the layout of Dir, with the same defaults as PullOptions.Layout
+    Layout *Layout
+}
+
+// Diff compares the files a pull would write against what is in a local
+// directory. A missing ConfigMap is compared as if it were empty.
+// DiffResult.HasDrift reports whether anything differs. Namespaces that
+// cannot be listed are returned in a *PartialError along with the results
+// of the others.
+func (c *Client) Diff(ctx context.Context, opts DiffOptions) ([]DiffResult, error) {
+    dir := opts.Dir
+    if dir == "" {
+        dir = "."
+    }
+    if opts.AllNamespaces {
+        results, failures, err := c.ops.DiffAllConfigMaps(ctx, dir, opts.Layout)
+        if err != nil {
+            return nil, err
+        }
+        return results, partial(failures)
+    }
+
+    result, err := c.ops.DiffConfigMap(ctx, c.namespaceOr(opts.Namespace), opts.Name, dir, opts.Layout)
+    if err != nil {
+        return nil, err
+    }
+    return []DiffResult{*result}, nil
+}
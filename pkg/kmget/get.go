@@ -0,0 +1,185 @@
+package kmget
+
+import (
+    "bytes"
+    "context"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "io"
+    "path"
+    "slices"
+    "strings"
+
+    "go.yaml.in/yaml/v3"
+)
+
+// GetOptions selects the object and keys Get returns
+type GetOptions struct {
+    // Name of the ConfigMap or Secret
+    Name string
+
+    // Namespace of the object, the Client's namespace when empty
+    Namespace string
+
+    // Kind is KindConfigMap (the default), KindSecret or KindAll. KindAll
+    // falls back to a Secret when there is no ConfigMap called Name.
+    Kind string
+
+    // Keys are glob patterns (path.Match syntax) of the keys whose values
+    // are returned. When empty, only the key names are.
+    Keys []string
+
+    // Pretty indents JSON values and re-indents YAML values, detected by
+    // the key's extension or, for JSON objects and arrays, by the content
+    Pretty bool
+}
+
+// Value is the value of one key
+type Value struct {
+    Key  string
+    Data []byte
+
+    // Binary is set for keys of a ConfigMap's binaryData
+    Binary bool
+}
+
+// GetResult holds the keys of an object and the selected values
+type GetResult struct {
+    Kind      string
+    Namespace string
+    Name      string
+
+    // Keys are all keys of the object, sorted
+    Keys []string
+
+    // Values are the values of the keys matching GetOptions.Keys, sorted
+    // by key
+    Values []Value
+}
+
+// Get reads the keys of a ConfigMap or Secret without writing any files.
+// A pattern that matches no key is an error.
+func (c *Client) Get(ctx context.Context, opts GetOptions) (*GetResult, error) {
+    withConfigMaps, withSecrets, err := selectKinds(opts.Kind)
+    if err != nil {
+        return nil, err
+    }
+    for _, pattern := range opts.Keys {
+        if _, err := path.Match(pattern, ""); err != nil {
+            return nil, fmt.Errorf("invalid key pattern '%s': %w", pattern, err)
+        }
+    }
+
+    namespace := c.namespaceOr(opts.Namespace)
+    result := &GetResult{Namespace: namespace, Name: opts.Name}
+    var values []Value
+
+    found := false
+    if withConfigMaps {
+        configMap, err := c.ops.GetConfigMap(ctx, namespace, opts.Name)
+        switch {
+        case err == nil:
+            found = true
+            result.Kind = KindConfigMap
+            for key, data := range configMap.Data {
+                values = append(values, Value{Key: key, Data: []byte(data)})
+            }
+            for key, data := range configMap.BinaryData {
+                values = append(values, Value{Key: key, Data: data, Binary: true})
+            }
+        case !(withSecrets && IsNotFound(err)):
+            return nil, err
+        }
+    }
+    if withSecrets && !found {
+        secret, err := c.ops.GetSecret(ctx, namespace, opts.Name)
+        if err != nil {
+            return nil, err
+        }
+        result.Kind = KindSecret
+        for key, data := range secret.Data {
+            values = append(values, Value{Key: key, Data: data})
+        }
+    }
+
+    slices.SortFunc(values, func(a, b Value) int { return strings.Compare(a.Key, b.Key) })
+    result.Keys = make([]string, 0, len(values))
+    for _, value := range values {
+        result.Keys = append(result.Keys, value.Key)
+    }
+
+    for _, pattern := range opts.Keys {
+        if !slices.ContainsFunc(values, func(v Value) bool { return matchKey(pattern, v.Key) }) {
+            return nil, fmt.Errorf("no key of %s '%s' in namespace '%s' matches '%s'", result.Kind, opts.Name, namespace, pattern)
+        }
+    }
+    for _, value := range values {
+        if !slices.ContainsFunc(opts.Keys, func(pattern string) bool { return matchKey(pattern, value.Key) }) {
+            continue
+        }
+        if opts.Pretty && !value.Binary {
+            value.Data = prettyPrint(value.Key, value.Data)
+        }
+        result.Values = append(result.Values, value)
+    }
+    return result, nil
+}
+
+// matchKey reports whether key matches the glob pattern
+func matchKey(pattern, key string) bool {
+    matched, _ := path.Match(pattern, key)
+    return matched
+}
+
+// prettyPrint indents data if it is JSON or YAML and returns it unchanged
+// otherwise, including when it does not parse
+func prettyPrint(key string, data []byte) []byte {
+    switch strings.ToLower(path.Ext(key)) {
+    case ".yaml", ".yml":
+        if pretty, err := prettyYAML(data); err == nil {
+            return pretty
+        }
+        return data
+    case ".json":
+    default:
+        // Only objects and arrays are sniffed, so plain values such as 42,
+        // true or "x" are left alone
+        trimmed := bytes.TrimLeft(data, " \t\r\n")
+        if len(trimmed) == 0 || (trimmed[0] != '{' && trimmed[0] != '[') || !json.Valid(data) {
+            return data
+        }
+    }
+
+    var buf bytes.Buffer
+    if err := json.Indent(&buf, data, "", "  "); err != nil {
+        return data
+    }
+    buf.WriteByte('\n')
+    return buf.Bytes()
+}
+
+// prettyYAML re-indents every document of data, keeping key order and
+// comments. It decodes into yaml.Node, which sigs.k8s.io/yaml cannot do: that
+// library round-trips through JSON and would drop comments and sort keys.
+func prettyYAML(data []byte) ([]byte, error) {
+    decoder := yaml.NewDecoder(bytes.NewReader(data))
+    var buf bytes.Buffer
+    encoder := yaml.NewEncoder(&buf)
+    encoder.SetIndent(2)
+    for {
+        var document yaml.Node
+        if err := decoder.Decode(&document); errors.Is(err, io.EOF) {
+            break
+        } else if err != nil {
+            return nil, err
+        }
+        if err := encoder.Encode(&document); err != nil {
+            return nil, err
+        }
+    }
+    if err := encoder.Close(); err != nil {
+        return nil, err
+    }
+    return buf.Bytes(), nil
+}
@@ -0,0 +1,198 @@
+// Package kmget is the Go API behind the kmget command. It lists, pulls, reads,
+// pushes and diffs ConfigMaps and Secrets without going through the CLI:
+//
+//     c, err := kmget.New(kmget.Options{Context: "prod"})
+//     if err != nil {
+//         return err
+//     }
+//     result, err := c.Pull(ctx, kmget.PullOptions{Name: "app-config", OutputDir: "./config"})
+//
+// Every method takes an option struct whose zero value is a sensible
+// default and returns a typed result. Bulk operations that record failures
+// with ContinueOnError return their full result along with a *PartialError.
+// Operations stop when ctx is done and their errors then wrap ctx's error.
+package kmget
+
+import (
+    "errors"
+    "fmt"
+    "strings"
+
+    apierrors "k8s.io/apimachinery/pkg/api/errors"
+    "k8s.io/client-go/kubernetes"
+    "kmget/pkg/client"
+    "kmget/pkg/configmap"
+)
+
+// Kinds of objects an operation selects
+const (
+    KindConfigMap = configmap.KindConfigMap
+    KindSecret    = configmap.KindSecret
+    KindAll       = "all"
+)
+
+// Result types shared with package configmap
+type (
+    ConfigMapInfo = configmap.ConfigMapInfo
+    SecretInfo    = configmap.SecretInfo
+    Failure       = configmap.Failure
+    PullResult    = configmap.PullAllResult
+    PushResult    = configmap.PushConfigMapResult
+    DiffResult    = configmap.DiffResult
+    Layout        = configmap.Layout
+)
+
+// Options selects the cluster a Client talks to. The zero value behaves
+// like kubectl without flags: the KUBECONFIG list or ~/.kube/config, its
+// current context and namespace, or in-cluster authentication.
+type Options struct {
+    Kubeconfig string
+
+    // Context, Cluster and User override the current kubeconfig context
+    // and the cluster and user it refers to
+    Context string
+    Cluster string
+    User    string
+
+    // Namespace is the default namespace of operations, the context's
+    // namespace when empty
+    Namespace string
+
+    // Impersonate and ImpersonateGroups act as another user or groups
+    Impersonate       string
+    ImpersonateGroups []string
+
+    // RequestTimeout is a duration such as 30s, "0" or empty for none
+    RequestTimeout string
+
+    // QPS and Burst limit the request rate towards the API server. Zero
+    // keeps the client-go defaults.
+    QPS   float32
+    Burst int
+}
+
+// Client runs kmget operations against one cluster
+type Client struct {
+    ops       *configmap.Operations
+    namespace string
+}
+
+// New connects to the cluster selected by opts
+func New(opts Options) (*Client, error) {
+    k8sClient, err := client.NewClient(&client.Config{
+        Kubeconfig:        opts.Kubeconfig,
+        Namespace:         opts.Namespace,
+        Context:           opts.Context,
+        Cluster:           opts.Cluster,
+        User:              opts.User,
+        Impersonate:       opts.Impersonate,
+        ImpersonateGroups: opts.ImpersonateGroups,
+        RequestTimeout:    opts.RequestTimeout,
+        QPS:               opts.QPS,
+        Burst:             opts.Burst,
+    })
+    if err != nil {
+        return nil, err
+    }
+    return NewForClientset(k8sClient.Clientset, k8sClient.Namespace), nil
+}
+
+// NewForClientset creates a Client on top of an existing clientset, e.g.
+// k8s.io/client-go/kubernetes/fake in tests. namespace is the default
+// namespace of operations.
+func NewForClientset(clientset kubernetes.Interface, namespace string, opts ...configmap.Option) *Client {
+    if namespace == "" {
+        namespace = "default"
+    }
+    return &Client{
+        ops:       configmap.NewOperations(clientset, opts...),
+        namespace: namespace,
+    }
+}
+
+// Namespace returns the default namespace of operations
+func (c *Client) Namespace() string {
+    return c.namespace
+}
+
+// Operations gives access to the lower-level operations the Client is
+// built on, e.g. to watch or restore ConfigMaps
+func (c *Client) Operations() *configmap.Operations {
+    return c.ops
+}
+
+// namespaceOr returns namespace, or the Client's default when empty
+func (c *Client) namespaceOr(namespace string) string {
+    if namespace == "" {
+        return c.namespace
+    }
+    return namespace
+}
+
+// ParseKind translates a kind as typed on the command line, such as cm,
+// secrets or all, into KindConfigMap, KindSecret or KindAll
+func ParseKind(kind string) (string, error) {
+    switch strings.ToLower(kind) {
+    case "", "configmap", "configmaps", "cm":
+        return KindConfigMap, nil
+    case "secret", "secrets":
+        return KindSecret, nil
+    case "all":
+        return KindAll, nil
+    default:
+        return "", fmt.Errorf("invalid kind %q (must be one of: configmap, secret, all)", kind)
+    }
+}
+
+// selectKinds reports which kinds kind selects, ConfigMaps when empty
+func selectKinds(kind string) (configMaps bool, secrets bool, err error) {
+    switch kind {
+    case "", KindConfigMap:
+        return true, false, nil
+    case KindSecret:
+        return false, true, nil
+    case KindAll:
+        return true, true, nil
+    default:
+        return false, false, fmt.Errorf("invalid kind %q (must be one of: %s, %s, %s)", kind, KindConfigMap, KindSecret, KindAll)
+    }
+}
+
+// PartialError is returned along with the result of a bulk operation that
+// completed, but failed for some namespaces or objects
+type PartialError struct {
+    Failures []Failure
+}
+
+func (e *PartialError) Error() string {
+    return fmt.Sprintf("%d namespace(s) or object(s) failed, first: %v", len(e.Failures), e.Failures[0].Error)
+}
+
+// Unwrap returns the errors of the individual failures
+func (e *PartialError) Unwrap() []error {
+    errs := make([]error, 0, len(e.Failures))
+    for _, failure := range e.Failures {
+        errs = append(errs, failure.Error)
+    }
+    return errs
+}
+
+// partial returns a *PartialError for failures, or nil when there are none
+func partial(failures []Failure) error {
+    if len(failures) == 0 {
+        return nil
+    }
+    return &PartialError{Failures: failures}
+}
+
+// IsPartial reports whether err is a *PartialError
+func IsPartial(err error) bool {
+    var partialErr *PartialError
+    return errors.As(err, &partialErr)
+}
+
+// IsNotFound reports whether err means that a ConfigMap, Secret or other
+// object does not exist
+func IsNotFound(err error) bool {
+    return apierrors.IsNotFound(err)
+}
@@ -0,0 +1,266 @@
+package kmget
+
+import (
+    "context"
+    "os"
+    "path/filepath"
+    "reflect"
+    "slices"
+    "testing"
+
+    corev1 "k8s.io/api/core/v1"
+    metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+    "k8s.io/client-go/kubernetes/fake"
+)
+
+func newTestClient() *Client {
+    return NewForClientset(fake.NewClientset(
+        &corev1.ConfigMap{
+            ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "shop"},
+            Data:       map[string]string{"app.yaml": "a"},
+        },
+        &corev1.ConfigMap{
+            ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "team"},
+            Data:       map[string]string{"web.yaml": "w"},
+        },
+        &corev1.Secret{
+            ObjectMeta: metav1.ObjectMeta{Name: "db", Namespace: "shop"},
+            Data:       map[string][]byte{"password": []byte("s3cret")},
+        },
+    ), "shop")
+}
+
+func TestList(t *testing.T) {
+    c := newTestClient()
+
+    result, err := c.List(context.Background(), ListOptions{Kind: KindAll})
+    if err != nil {
+        t.Fatalf("List failed: %v", err)
+    }
+    if got := result.ConfigMaps["shop"]; len(got) != 1 || got[0].Name != "app" {
+        t.Errorf("ConfigMaps = %+v, want shop/app", result.ConfigMaps)
+    }
+    if got := result.Secrets["shop"]; len(got) != 1 || got[0].Name != "db" {
+        t.Errorf("Secrets = %+v, want shop/db", result.Secrets)
+    }
+
+    all, err := c.List(context.Background(), ListOptions{AllNamespaces: true})
+    if err != nil {
+        t.Fatalf("List across all namespaces failed: %v", err)
+    }
+    if len(all.ConfigMaps) != 2 || all.Secrets != nil {
+        t.Errorf("result = %+v, want the ConfigMaps of shop and team only", all)
+    }
+
+    if _, err := c.List(context.Background(), ListOptions{Kind: "pods"}); err == nil {
+        t.Error("List with an invalid kind succeeded")
+    }
+}
+
+func TestPullAndDiff(t *testing.T) {
+    c := newTestClient()
+    root := t.TempDir()
+
+    result, err := c.Pull(context.Background(), PullOptions{Name: "app", OutputDir: root})
+    if err != nil {
+        t.Fatalf("Pull failed: %v", err)
+    }
+    if len(result.Results) != 1 || result.Results[0].Kind != KindConfigMap {
+        t.Fatalf("results = %+v, want the ConfigMap app", result.Results)
+    }
+    assertFile(t, filepath.Join(root, "app.yaml"), "a")
+
+    diffs, err := c.Diff(context.Background(), DiffOptions{Name: "app", Dir: root})
+    if err != nil {
+        t.Fatalf("Diff failed: %v", err)
+    }
+    if len(diffs) != 1 || diffs[0].HasDrift() {
+        t.Errorf("diffs = %+v, want no drift right after a pull", diffs)
+    }
+
+    if err := os.WriteFile(filepath.Join(root, "app.yaml"), []byte("changed"), 0644); err != nil {
+        t.Fatal(err)
+    }
+    diffs, err = c.Diff(context.Background(), DiffOptions{Name: "app", Dir: root})
+    if err != nil {
+        t.Fatalf("Diff failed: %v", err)
+    }
+    if !diffs[0].HasDrift() {
+        t.Error("Diff did not report a locally changed key")
+    }
+}
+
+func TestPullAllKindsAndDiff(t *testing.T) {
+    c := newTestClient()
+    root := t.TempDir()
+
+    if _, err := c.Pull(context.Background(), PullOptions{AllNamespaces: true, Kind: KindAll, OutputDir: root}); err != nil {
+        t.Fatalf("Pull failed: %v", err)
+    }
+    assertFile(t, filepath.Join(root, "shop", "password"), "s3cret")
+
+    // the Secret's file is recorded in the backup metadata, not added
+    diffs, err := c.Diff(context.Background(), DiffOptions{AllNamespaces: true, Dir: root})
+    if err != nil {
+        t.Fatalf("Diff failed: %v", err)
+    }
+    for _, result := range diffs {
+        if result.HasDrift() {
+            t.Errorf("%s/%s drifted: %+v, want no drift right after a pull", result.Namespace, result.ConfigMapName, result.Changes)
+        }
+    }
+}
+
+func TestPullKinds(t *testing.T) {
+    c := newTestClient()
+
+    // db only exists as a Secret, which is enough with KindAll
+    root := t.TempDir()
+    result, err := c.Pull(context.Background(), PullOptions{Name: "db", Kind: KindAll, OutputDir: root})
+    if err != nil {
+        t.Fatalf("Pull with KindAll failed: %v", err)
+    }
+    if len(result.Results) != 1 || result.Results[0].Kind != KindSecret {
+        t.Errorf("results = %+v, want the Secret db", result.Results)
+    }
+    assertFile(t, filepath.Join(root, "password"), "s3cret")
+
+    if _, err := c.Pull(context.Background(), PullOptions{Name: "db", OutputDir: t.TempDir()}); !IsNotFound(err) {
+        t.Errorf("Pull of a missing ConfigMap = %v, want a not found error", err)
+    }
+    if _, err := c.Pull(context.Background(), PullOptions{Name: "db", Kind: KindSecret, As: PullAsManifest}); err == nil {
+        t.Error("Pull of a Secret as manifest succeeded")
+    }
+
+    all, err := c.Pull(context.Background(), PullOptions{AllNamespaces: true, OutputDir: t.TempDir()})
+    if err != nil {
+        t.Fatalf("Pull across all namespaces failed: %v", err)
+    }
+    if len(all.Results) != 2 || len(all.Failures) != 0 {
+        t.Errorf("result = %+v, want shop/app and team/web without failures", all)
+    }
+}
+
+func TestPullPartial(t *testing.T) {
+    c := NewForClientset(fake.NewClientset(
+        &corev1.ConfigMap{
+            ObjectMeta: metav1.ObjectMeta{Name: "api", Namespace: "shop"},
+            Data:       map[string]string{"config.yaml": "api"},
+        },
+        &corev1.ConfigMap{
+            ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "shop"},
+            Data:       map[string]string{"config.yaml": "web"},
+        },
+    ), "")
+
+    result, err := c.Pull(context.Background(), PullOptions{AllNamespaces: true, ContinueOnError: true, OutputDir: t.TempDir()})
+    if !IsPartial(err) {
+        t.Fatalf("Pull with a collision = %v, want a *PartialError", err)
+    }
+    if result == nil || len(result.Failures) != 1 || result.Failures[0].Name != "web" {
+        t.Errorf("result = %+v, want the colliding key of web as failure", result)
+    }
+}
+
+func TestPullArchive(t *testing.T) {
+    c := newTestClient()
+    archive := filepath.Join(t.TempDir(), "backup.tgz")
+
+    result, err := c.Pull(context.Background(), PullOptions{AllNamespaces: true, Archive: archive})
+    if err != nil {
+        t.Fatalf("Pull into an archive failed: %v", err)
+    }
+    if len(result.Results) != 2 {
+        t.Errorf("results = %+v, want shop/app and team/web", result.Results)
+    }
+    if info, err := os.Stat(archive); err != nil || info.Size() == 0 {
+        t.Errorf("archive was not written: %v", err)
+    }
+
+    missing := filepath.Join(t.TempDir(), "missing.zip")
+    if _, err := c.Pull(context.Background(), PullOptions{Name: "nope", Archive: missing}); !IsNotFound(err) {
+        t.Errorf("Pull of a missing ConfigMap = %v, want a not found error", err)
+    }
+    if _, err := os.Stat(missing); !os.IsNotExist(err) {
+        t.Error("the archive of a failed pull was left behind")
+    }
+}
+
+func TestGet(t *testing.T) {
+    c := NewForClientset(fake.NewClientset(
+        &corev1.ConfigMap{
+            ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "shop"},
+            Data: map[string]string{
+                "app.yaml":      "server:\n    port: 80 # http\n",
+                "settings.json": `{"debug":true}`,
+                "README":        "plain",
+                "REPLICAS":      "42",
+                "FILTER":        ` [1,2]`,
+            },
+            BinaryData: map[string][]byte{"logo.png": {0x89, 'P', 'N', 'G'}},
+        },
+    ), "shop")
+    ctx := context.Background()
+
+    listed, err := c.Get(ctx, GetOptions{Name: "app"})
+    if err != nil {
+        t.Fatalf("Get failed: %v", err)
+    }
+    if want := []string{"FILTER", "README", "REPLICAS", "app.yaml", "logo.png", "settings.json"}; !slices.Equal(listed.Keys, want) || listed.Values != nil {
+        t.Errorf("result = %+v, want keys %v and no values", listed, want)
+    }
+
+    binary, err := c.Get(ctx, GetOptions{Name: "app", Keys: []string{"logo.png"}, Pretty: true})
+    if err != nil {
+        t.Fatalf("Get of a binary key failed: %v", err)
+    }
+    if len(binary.Values) != 1 || !binary.Values[0].Binary || string(binary.Values[0].Data) != "\x89PNG" {
+        t.Errorf("values = %+v, want the raw bytes of logo.png", binary.Values)
+    }
+
+    pretty, err := c.Get(ctx, GetOptions{Name: "app", Keys: []string{"*.yaml", "*.json"}, Pretty: true})
+    if err != nil {
+        t.Fatalf("Get with patterns failed: %v", err)
+    }
+    want := []Value{
+        {Key: "app.yaml", Data: []byte("server:\n  port: 80 # http\n")},
+        {Key: "settings.json", Data: []byte("{\n  \"debug\": true\n}\n")},
+    }
+    if !reflect.DeepEqual(pretty.Values, want) {
+        t.Errorf("values = %+v, want %+v", pretty.Values, want)
+    }
+
+    sniffed, err := c.Get(ctx, GetOptions{Name: "app", Keys: []string{"FILTER", "REPLICAS"}, Pretty: true})
+    if err != nil {
+        t.Fatalf("Get of keys without an extension failed: %v", err)
+    }
+    want = []Value{
+        {Key: "FILTER", Data: []byte("[\n  1,\n  2\n]\n")},
+        {Key: "REPLICAS", Data: []byte("42")},
+    }
+    if !reflect.DeepEqual(sniffed.Values, want) {
+        t.Errorf("values = %+v, want %+v (plain JSON scalars are left alone)", sniffed.Values, want)
+    }
+
+    if _, err := c.Get(ctx, GetOptions{Name: "app", Keys: []string{"missing"}}); err == nil {
+        t.Error("Get of a missing key succeeded")
+    }
+    if _, err := c.Get(ctx, GetOptions{Name: "app", Keys: []string{"["}}); err == nil {
+        t.Error("Get with an invalid pattern succeeded")
+    }
+    if _, err := c.Get(ctx, GetOptions{Name: "nope", Kind: KindAll}); !IsNotFound(err) {
+        t.Errorf("Get of a missing object = %v, want a not found error", err)
+    }
+}
+
+func assertFile(t *testing.T, path, want string) {
+    t.Helper()
+    got, err := os.ReadFile(path)
+    if err != nil {
+        t.Errorf("reading %s: %v", path, err)
+        return
+    }
+    if string(got) != want {
+        t.Errorf("%s = %q, want %q", path, got, want)
+    }
+}
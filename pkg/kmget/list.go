@@ -0,0 +1,116 @@
+package kmget
+
+import (
+    "context"
+    "maps"
+
+    metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+    "kmget/pkg/configmap"
+)
+
+// ListOptions selects the objects List returns
+type ListOptions struct {
+    // Namespace to list, the Client's namespace when empty. Ignored with
+    // AllNamespaces.
+    Namespace     string
+    AllNamespaces bool
+
+    // Kind is KindConfigMap (the default), KindSecret or KindAll
+    Kind string
+
+    LabelSelector string
+    FieldSelector string
+
+    // ContinueOnError records namespaces that cannot be listed as failures
+    // instead of aborting
+    ContinueOnError bool
+
+    // WithConsumers looks up the workloads using every ConfigMap
+    WithConsumers bool
+}
+
+// ListResult holds the listed objects by namespace
+type ListResult struct {
+    ConfigMaps map[string][]ConfigMapInfo `json:"configMaps,omitempty"`
+    Secrets    map[string][]SecretInfo    `json:"secrets,omitempty"`
+    Failures   []Failure                  `json:"failures"`
+
+    // Warnings are problems that did not stop the listing, such as
+    // consumers that could not be looked up
+    Warnings []error `json:"-"`
+}
+
+// List lists ConfigMaps and Secrets. Namespaces that failed with
+// ContinueOnError are returned in a *PartialError along with the result.
+func (c *Client) List(ctx context.Context, opts ListOptions) (*ListResult, error) {
+    withConfigMaps, withSecrets, err := selectKinds(opts.Kind)
+    if err != nil {
+        return nil, err
+    }
+
+    namespace := c.namespaceOr(opts.Namespace)
+    listOptions := configmap.ListOptions{
+        LabelSelector:   opts.LabelSelector,
+        FieldSelector:   opts.FieldSelector,
+        ContinueOnError: opts.ContinueOnError,
+    }
+    result := &ListResult{Failures: []Failure{}}
+
+    if withConfigMaps {
+        if opts.AllNamespaces {
+            configMaps, failures, err := c.ops.ListAllConfigMaps(ctx, listOptions)
+            if err != nil {
+                return nil, err
+            }
+            result.ConfigMaps = configMaps
+            result.Failures = append(result.Failures, failures...)
+        } else {
+            configMaps, err := c.ops.ListConfigMaps(ctx, namespace, listOptions)
+            if err != nil {
+                return nil, err
+            }
+            result.ConfigMaps = map[string][]ConfigMapInfo{namespace: configMaps}
+        }
+
+        if opts.WithConsumers {
+            scope := namespace
+            if opts.AllNamespaces {
+                scope = metav1.NamespaceAll
+            }
+            c.addConsumers(ctx, result, scope)
+        }
+    }
+
+    if withSecrets {
+        if opts.AllNamespaces {
+            secrets, failures, err := c.ops.ListAllSecrets(ctx, listOptions)
+            if err != nil {
+                return nil, err
+            }
+            result.Secrets = secrets
+            result.Failures = append(result.Failures, failures...)
+        } else {
+            secrets, err := c.ops.ListSecrets(ctx, namespace, listOptions)
+            if err != nil {
+                return nil, err
+            }
+            result.Secrets = map[string][]SecretInfo{namespace: secrets}
+        }
+    }
+
+    return result, partial(result.Failures)
+}
+
+// addConsumers fills in the consumers of the listed ConfigMaps. Failing to
+// scan the workloads is only a warning.
+func (c *Client) addConsumers(ctx context.Context, result *ListResult, namespace string) {
+    consumers, err := c.ops.FindConsumers(ctx, namespace)
+    if err != nil {
+        result.Warnings = append(result.Warnings, err)
+        return
+    }
+    for configMaps := range maps.Values(result.ConfigMaps) {
+        configmap.AddConsumers(configMaps, consumers)
+    }
+}
+
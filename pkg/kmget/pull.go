@@ -0,0 +1,232 @@
+package kmget
+
+import (
+    "context"
+    "fmt"
+    "os"
+
+    "kmget/pkg/configmap"
+)
+
+// Pull output formats
+const (
+    PullAsFiles    = configmap.PullAsFiles
+    PullAsManifest = configmap.PullAsManifest
+)
+
+// PullOptions selects the objects Pull writes and how they are written
+type PullOptions struct {
+    // Name of the ConfigMap or Secret to pull. When empty, every object of
+    // the namespace matching the selectors is pulled.
+    Name string
+
+    // Namespace to pull from, the Client's namespace when empty. Ignored
+    // with AllNamespaces.
+    Namespace     string
+    AllNamespaces bool
+
+    // Kind is KindConfigMap (the default), KindSecret or KindAll. With
+    // KindAll a named pull succeeds as long as one of the two exists.
+    Kind string
+
+    // OutputDir is the directory written to, the working directory when
+    // empty. It is not used with Archive or Sink.
+    OutputDir string
+
+    // Archive is a .tar.gz, .tgz, .tar or .zip file written instead of
+    // OutputDir. All objects of the pull, and the backup metadata of pulls
+    // across all namespaces, go into this one archive.
+    Archive string
+
+    // Sink receives the files instead of OutputDir, e.g. a
+    // configmap.TarSink streaming to stdout. The caller closes it.
+    Sink configmap.Sink
+
+    LabelSelector string
+    FieldSelector string
+
+    // ContinueOnError records failing namespaces and objects instead of
+    // aborting a pull across all namespaces
+    ContinueOnError bool
+
+    // As is PullAsFiles (the default) or PullAsManifest; SingleFile writes
+    // all manifests into one file
+    As         string
+    SingleFile bool
+
+    // Layout maps keys to paths, see configmap.ParseLayout. When nil,
+    // single-namespace pulls are flat and all-namespace pulls use ns/key.
+    Layout *Layout
+
+    // OnCollision is configmap.CollisionFail (the default) or
+    // configmap.CollisionRename
+    OnCollision string
+
+    // Concurrency is the number of files written in parallel
+    Concurrency int
+
+    // Atomic swaps a ..data symlink like a ConfigMap volume
+    Atomic bool
+}
+
+// pullOptions converts opts to the options of package configmap. ConfigMaps
+// and Secrets share one tracker so they cannot overwrite each other's files.
+func (p PullOptions) pullOptions() configmap.PullOptions {
+    return configmap.PullOptions{
+        ListOptions: configmap.ListOptions{
+            LabelSelector:   p.LabelSelector,
+            FieldSelector:   p.FieldSelector,
+            ContinueOnError: p.ContinueOnError,
+        },
+        As:          p.As,
+        SingleFile:  p.SingleFile,
+        Layout:      p.Layout,
+        OnCollision: p.OnCollision,
+        Tracker:     configmap.NewPathTracker(),
+        Concurrency: p.Concurrency,
+        Atomic:      p.Atomic,
+        Sink:        p.Sink,
+    }
+}
+
+// Pull writes ConfigMaps and Secrets to local files. Failures are only
+// recorded for pulls across all namespaces; those that failed with
+// ContinueOnError are returned in a *PartialError along with the result.
+// An interrupted pull returns what it wrote so far along with the error; an
+// Archive is still completed so that it can be read.
+func (c *Client) Pull(ctx context.Context, opts PullOptions) (result *PullResult, err error) {
+    withConfigMaps, withSecrets, err := selectKinds(opts.Kind)
+    if err != nil {
+        return nil, err
+    }
+    if withSecrets && opts.As == PullAsManifest {
+        return nil, fmt.Errorf("manifests are only supported for ConfigMaps")
+    }
+    if opts.Atomic && withConfigMaps && withSecrets {
+        return nil, fmt.Errorf("an atomic pull writes a single kind per output directory")
+    }
+
+    outputDir := opts.OutputDir
+    if outputDir == "" {
+        outputDir = "."
+    }
+    if opts.Archive != "" && opts.Sink != nil {
+        return nil, fmt.Errorf("an archive and a sink cannot be combined")
+    }
+
+    namespace := c.namespaceOr(opts.Namespace)
+    pullOptions := opts.pullOptions()
+    if opts.Archive != "" {
+        if err := pullOptions.Validate(); err != nil {
+            return nil, err
+        }
+        sink, createErr := configmap.CreateArchive(opts.Archive)
+        if createErr != nil {
+            return nil, createErr
+        }
+        pullOptions.Sink = sink
+        defer func() {
+            if closeErr := sink.Close(); err == nil || (IsPartial(err) && closeErr != nil) {
+                err = closeErr
+            }
+            if err != nil && (result == nil || len(result.Results) == 0) {
+                // nothing was pulled, e.g. the ConfigMap does not exist
+                os.Remove(opts.Archive)
+            }
+        }()
+    }
+
+    switch {
+    case opts.AllNamespaces:
+        return c.pullAll(ctx, outputDir, pullOptions, withConfigMaps, withSecrets)
+    case opts.Name == "":
+        return c.pullNamespace(ctx, namespace, outputDir, pullOptions, withConfigMaps, withSecrets)
+    default:
+        return c.pullNamed(ctx, namespace, opts.Name, outputDir, pullOptions, withConfigMaps, withSecrets)
+    }
+}
+
+// pullAll pulls the selected kinds from all namespaces. With both kinds the
+// backup metadata records the Secrets too, so that diff knows their files.
+func (c *Client) pullAll(ctx context.Context, outputDir string, opts configmap.PullOptions, withConfigMaps, withSecrets bool) (all *PullResult, err error) {
+    all = &PullResult{Results: []configmap.PullConfigMapResult{}, Failures: []Failure{}}
+    if withConfigMaps && withSecrets && opts.As != PullAsManifest {
+        opts.Backup = configmap.NewBackupRecorder()
+        defer func() {
+            sink := opts.Sink
+            if sink == nil {
+                sink = configmap.NewDirSink(outputDir)
+            }
+            if writeErr := opts.Backup.Write(sink); writeErr != nil && (err == nil || IsPartial(err)) {
+                err = fmt.Errorf("failed to write backup metadata: %w", writeErr)
+            }
+        }()
+    }
+    if withConfigMaps {
+        var configMapResults *PullResult
+        configMapResults, err = c.ops.PullAllConfigMaps(ctx, outputDir, opts)
+        if configMapResults != nil {
+            all.Results = append(all.Results, configMapResults.Results...)
+            all.Failures = append(all.Failures, configMapResults.Failures...)
+        }
+    }
+    if withSecrets && err == nil {
+        var secretResults *PullResult
+        secretResults, err = c.ops.PullAllSecrets(ctx, outputDir, opts)
+        if secretResults != nil {
+            all.Results = append(all.Results, secretResults.Results...)
+            all.Failures = append(all.Failures, secretResults.Failures...)
+        }
+    }
+    if err != nil {
+        return all, err
+    }
+    return all, partial(all.Failures)
+}
+
+// pullNamespace pulls the objects of namespace matching the selectors
+func (c *Client) pullNamespace(ctx context.Context, namespace, outputDir string, opts configmap.PullOptions, withConfigMaps, withSecrets bool) (*PullResult, error) {
+    result := &PullResult{Results: []configmap.PullConfigMapResult{}, Failures: []Failure{}}
+    var err error
+    if withConfigMaps {
+        var configMapResults []configmap.PullConfigMapResult
+        configMapResults, err = c.ops.PullConfigMaps(ctx, namespace, outputDir, opts)
+        result.Results = append(result.Results, configMapResults...)
+    }
+    if withSecrets && err == nil {
+        var secretResults []configmap.PullConfigMapResult
+        secretResults, err = c.ops.PullSecrets(ctx, namespace, outputDir, opts)
+        result.Results = append(result.Results, secretResults...)
+    }
+    return result, err
+}
+
+// pullNamed pulls the ConfigMap and/or Secret called name
+func (c *Client) pullNamed(ctx context.Context, namespace, name, outputDir string, opts configmap.PullOptions, withConfigMaps, withSecrets bool) (*PullResult, error) {
+    // With both kinds a missing ConfigMap or Secret is fine as long as at
+    // least one of them exists.
+    tolerateMissing := withConfigMaps && withSecrets
+    result := &PullResult{Results: []configmap.PullConfigMapResult{}, Failures: []Failure{}}
+
+    pulls := []func(context.Context, string, string, string, configmap.PullOptions) (*configmap.PullConfigMapResult, error){}
+    if withConfigMaps {
+        pulls = append(pulls, c.ops.PullConfigMap)
+    }
+    if withSecrets {
+        pulls = append(pulls, c.ops.PullSecret)
+    }
+    for _, pull := range pulls {
+        pulled, err := pull(ctx, namespace, name, outputDir, opts)
+        if pulled != nil {
+            result.Results = append(result.Results, *pulled)
+        }
+        if err != nil && !(tolerateMissing && IsNotFound(err)) {
+            return result, err
+        }
+    }
+
+    if len(result.Results) == 0 {
+        return nil, fmt.Errorf("no ConfigMap or Secret named '%s' in namespace '%s'", name, namespace)
+    }
+    return result, nil
+}
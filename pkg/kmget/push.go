@@ -0,0 +1,26 @@
+package kmget
+
+import "context"
+
+// PushOptions selects the ConfigMap Push writes and the files it is made of
+type PushOptions struct {
+    // Name of the ConfigMap to create or update
+    Name string
+
+    // Namespace of the ConfigMap, the Client's namespace when empty
+    Namespace string
+
+    // Dir holds one file per key, the working directory when empty
+    Dir string
+}
+
+// Push creates or updates a ConfigMap from the files in a directory with
+// server-side apply. When some files cannot be used nothing is applied and
+// the result reports them along with the error.
+func (c *Client) Push(ctx context.Context, opts PushOptions) (*PushResult, error) {
+    dir := opts.Dir
+    if dir == "" {
+        dir = "."
+    }
+    return c.ops.PushConfigMap(ctx, c.namespaceOr(opts.Namespace), opts.Name, dir)
+}